@@ -0,0 +1,52 @@
+package fuzzhelper
+
+import (
+	"reflect"
+)
+
+// WithAliasing allows every pointer to reuse a pointer previously allocated
+// by the same call to Fill, instead of always allocating a new value.
+// Individual fields can be given their own setting with a fuzz-alias:"true"
+// tag, which takes precedence over this option.
+//
+// An aliasing pointer consumes a control byte, after its nil control byte
+// if it has one. If the control byte is odd, and a pointer of the same type
+// has already been allocated, the pointer is set to one of the previously
+// allocated pointers and the value it points to is not filled again.
+// Otherwise a new value is allocated as normal. The root passed to Fill can
+// also be reused.
+//
+// This allows graphs with shared values and cycles to be filled, rather
+// than only trees.
+func WithAliasing(enabled bool) Option {
+	return func(config *fillConfig) {
+		config.aliasing = enabled
+	}
+}
+
+// Returns true if a pointer with tags may reuse a previously allocated
+// pointer
+func (config fillConfig) aliased(tags fuzzTags) bool {
+	if tags.alias.wasSet {
+		return tags.alias.enabled
+	}
+	return config.aliasing
+}
+
+// The pointers allocated by a single call to Fill, by type, in the order
+// they were allocated
+type pointerHistory map[reflect.Type][]reflect.Value
+
+func (h pointerHistory) record(value reflect.Value) {
+	h[value.Type()] = append(h[value.Type()], value)
+}
+
+// Returns the index of the previously allocated pointer equal to value
+func (h pointerHistory) index(value reflect.Value) int {
+	for i, candidate := range h[value.Type()] {
+		if candidate.Pointer() == value.Pointer() {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,117 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type aliasGraph struct {
+	Left  *aliasNode
+	Right *aliasNode
+}
+
+type aliasNode struct {
+	Value int8
+	Next  *aliasNode
+}
+
+type aliasTagNode struct {
+	Value int8
+	Next  *aliasTagNode `fuzz-alias:"true"`
+	Child *aliasTagNode
+}
+
+func TestFill_Aliasing(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// Left allocates a new node
+	c.pushNoSelector()
+	// Right reuses the node allocated for Left
+	c.pushSelector(0)
+	// The shared node, which points to itself
+	c.pushInt64(5, bytesFor8)
+	c.pushSelector(0)
+
+	val := aliasGraph{}
+	Fill(&val, c.getRawBytes(), WithAliasing(true))
+
+	require.NotNil(t, val.Left)
+	assert.Same(t, val.Left, val.Right)
+	assert.Same(t, val.Left, val.Left.Next)
+	assert.Equal(t, int8(5), val.Left.Value)
+}
+
+func TestFill_AliasTag(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// The root's fields, Next points back to the root
+	c.pushInt64(1, bytesFor8)
+	c.pushSelector(0)
+	// The root's Child, which is never aliased
+	c.pushInt64(2, bytesFor8)
+	// The selector picks from the root and its Child
+	c.pushSelector(1)
+
+	val := aliasTagNode{}
+	Fill(&val, c.getRawBytes())
+
+	assert.Same(t, &val, val.Next)
+	require.NotNil(t, val.Child)
+	assert.Equal(t, int8(2), val.Child.Value)
+	assert.Same(t, val.Child, val.Child.Next)
+	assert.Nil(t, val.Child.Child)
+}
+
+func TestEncode_Aliasing(t *testing.T) {
+	shared := &aliasNode{Value: 3}
+	shared.Next = &aliasNode{Value: 4, Next: shared}
+	val := aliasGraph{Left: shared, Right: shared}
+
+	encoded, err := Encode(&val, WithAliasing(true))
+	require.NoError(t, err)
+
+	filled := aliasGraph{}
+	Fill(&filled, encoded, WithAliasing(true))
+
+	assert.Same(t, filled.Left, filled.Right)
+	assert.Equal(t, int8(3), filled.Left.Value)
+	assert.Equal(t, int8(4), filled.Left.Next.Value)
+	assert.Same(t, filled.Left, filled.Left.Next.Next)
+}
+
+func TestEncode_SharedWithoutAliasing(t *testing.T) {
+	type pair struct {
+		First  *int8
+		Second *int8
+	}
+	shared := int8(3)
+	val := pair{First: &shared, Second: &shared}
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+
+	// The shared value is encoded twice
+	filled := pair{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+	assert.NotSame(t, filled.First, filled.Second)
+}
+
+func TestEncode_CycleWithoutAliasing(t *testing.T) {
+	node := &aliasNode{Value: 3}
+	node.Next = node
+	val := aliasGraph{Left: node}
+
+	_, err := Encode(&val)
+	assert.EqualError(t, err, "cannot encode *(aliasGraph).Left(*aliasNode).Next (*aliasNode): it points to a value containing it, which requires aliasing")
+}
+
+type badAliasTag struct {
+	PointerField *int `fuzz-alias:"sometimes"`
+}
+
+func TestFill_BadAliasTag(t *testing.T) {
+	assert.PanicsWithError(t, `fuzzhelper.badAliasTag.PointerField has invalid fuzz-alias "sometimes", must be true or false`, func() {
+		Fill(&badAliasTag{}, []byte{1})
+	})
+}
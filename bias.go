@@ -0,0 +1,123 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+)
+
+// WithEdgeBias enables edge biased numbers for every int, uint and float
+// which isn't filled from a method. Individual fields can be biased with a
+// fuzz-bias:"edges" tag instead.
+//
+// A biased number consumes an extra control byte before its value. If the
+// control byte is odd the number is taken from a table of edge values for
+// its range, such as the minimum, maximum, minimum+1, maximum-1, 0, -1 and
+// powers of two. Otherwise the number is consumed as normal. Edge values
+// are rarely produced by wide ranges, but are where off-by-one and overflow
+// bugs hide.
+func WithEdgeBias(enabled bool) Option {
+	return func(config *fillConfig) {
+		config.edgeBias = enabled
+	}
+}
+
+const edgeBiasTag = "edges"
+
+func newBiasTag(structVal reflect.Value, field reflect.StructField, tag string) bool {
+	valStr, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return false
+	}
+
+	if valStr != edgeBiasTag {
+		panic(fmt.Errorf("%s.%s has invalid %s %q, must be %q", structVal.Type(), field.Name, tag, valStr, edgeBiasTag))
+	}
+	return true
+}
+
+// Returns true if the number for tags should be edge biased
+func (config fillConfig) biased(tags fuzzTags) bool {
+	return config.edgeBias || tags.edgeBias
+}
+
+// Returns the edge values for an int, within r if it is valid or within the
+// bounds of the int's type otherwise.
+func intEdges(value reflect.Value, r intTagRange) []int64 {
+	bits := value.Type().Bits()
+	typeMin := int64(-1) << (bits - 1)
+	parts := []interval[int64]{{lo: typeMin, hi: -(typeMin + 1)}}
+	if r = r.within(value); r.valid() {
+		parts = r.parts()
+	}
+
+	candidates := []int64{}
+	for _, i := range parts {
+		candidates = append(candidates, i.lo, i.hi, i.lo+1, i.hi-1)
+	}
+	candidates = append(candidates, 0, -1, 1)
+	for i := 1; i < bits-1; i++ {
+		candidates = append(candidates, int64(1)<<i, -(int64(1) << i))
+	}
+
+	return edgesWithin(candidates, parts)
+}
+
+// Returns the edge values for a uint, within r if it is valid or within the
+// bounds of the uint's type otherwise.
+func uintEdges(value reflect.Value, r uintTagRange) []uint64 {
+	bits := value.Type().Bits()
+	parts := []interval[uint64]{{lo: 0, hi: uint64(math.MaxUint64) >> (64 - bits)}}
+	if r = r.within(value); r.valid() {
+		parts = r.parts()
+	}
+
+	candidates := []uint64{}
+	for _, i := range parts {
+		candidates = append(candidates, i.lo, i.hi, i.lo+1, i.hi-1)
+	}
+	candidates = append(candidates, 0, 1)
+	for i := 1; i < bits; i++ {
+		candidates = append(candidates, uint64(1)<<i, (uint64(1)<<i)-1)
+	}
+
+	return edgesWithin(candidates, parts)
+}
+
+// Returns the edge values for a float. Within a valid range r these are
+// the bounds, their nearest neighbours and the small integers. Without a
+// range the infinities, NaN and the extremes of the float's type are
+// included.
+func floatEdges(value reflect.Value, r floatTagRange) []float64 {
+	if r = r.within(value); r.valid() {
+		candidates := []float64{}
+		for _, i := range r.parts() {
+			candidates = append(candidates, i.lo, i.hi, math.Nextafter(i.lo, i.hi), math.Nextafter(i.hi, i.lo))
+		}
+		candidates = append(candidates, 0, -1, 1)
+
+		return edgesWithin(candidates, r.parts())
+	}
+
+	maxFloat, smallest := math.MaxFloat64, math.SmallestNonzeroFloat64
+	if value.Type().Size() == bytesFor32 {
+		maxFloat, smallest = math.MaxFloat32, math.SmallestNonzeroFloat32
+	}
+	return []float64{
+		0, math.Copysign(0, -1), 1, -1,
+		maxFloat, -maxFloat, smallest, -smallest,
+		math.Inf(1), math.Inf(-1), math.NaN(),
+	}
+}
+
+// Returns the candidates which are in the intervals, without duplicates
+func edgesWithin[T int64 | uint64 | float64](candidates []T, intervals []interval[T]) []T {
+	edges := []T{}
+	for _, edge := range candidates {
+		if intervalsContain(intervals, edge) && !slices.Contains(edges, edge) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
@@ -0,0 +1,111 @@
+package fuzzhelper
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type biasStruct struct {
+	IntField   int8    `fuzz-bias:"edges"`
+	RangeField int64   `fuzz-int-range:"-1000,1000000" fuzz-bias:"edges"`
+	UintField  uint16  `fuzz-uint-range:"10,20" fuzz-bias:"edges"`
+	FloatField float32 `fuzz-bias:"edges"`
+	PlainField int8
+}
+
+func TestFill_EdgeBiasTag(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// IntField, the maximum int8
+	c.pushSelector(1)
+	// RangeField, the range minimum+1
+	c.pushSelector(2)
+	// UintField, a normal value
+	c.pushBytes([]byte{0})
+	c.pushUint64(3, bytesFor16)
+	// FloatField, positive infinity
+	c.pushSelector(8)
+	// PlainField is not biased
+	c.pushInt64(-3, bytesFor8)
+
+	val := biasStruct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.Equal(t, biasStruct{
+		IntField:   math.MaxInt8,
+		RangeField: -999,
+		UintField:  13,
+		FloatField: float32(math.Inf(1)),
+		PlainField: -3,
+	}, val)
+}
+
+func TestFill_EdgeBiasOption(t *testing.T) {
+	type plainStruct struct {
+		IntField  int16 `fuzz-int-range:"-50,50"`
+		UintField uint8
+	}
+
+	c := newByteConsumer([]byte{})
+	// IntField, the range maximum-1
+	c.pushSelector(3)
+	// UintField, the selector wraps around the edge values
+	c.pushSelector(len(uintEdges(reflect.ValueOf(uint8(0)), uintTagRange{})))
+
+	val := plainStruct{}
+	Fill(&val, c.getRawBytes(), WithEdgeBias(true))
+
+	assert.Equal(t, plainStruct{IntField: 49, UintField: 0}, val)
+}
+
+func TestEdges(t *testing.T) {
+	assert.Equal(t, []int64{-4, 4, -3, 3, 0, -1, 1, 2, -2}, intEdges(reflect.ValueOf(int64(0)), intTagRange{wasSet: true, intMin: -4, intMax: 4}))
+	assert.Equal(t, []uint64{0, 255, 1, 254, 2, 4, 3, 8, 7, 16, 15, 32, 31, 64, 63, 128, 127}, uintEdges(reflect.ValueOf(uint8(0)), uintTagRange{}))
+	assert.Equal(t, []float64{1, 2, math.Nextafter(1, 2), math.Nextafter(2, 1)}, floatEdges(reflect.ValueOf(float64(0)), floatTagRange{wasSet: true, floatMin: 1, floatMax: 2}))
+
+	// Every edge value is within the type's bounds, and the range
+	for _, edge := range intEdges(reflect.ValueOf(int8(0)), intTagRange{wasSet: true, intMin: -1000, intMax: 5}) {
+		assert.True(t, edge >= math.MinInt8 && edge <= 5)
+	}
+}
+
+func TestEncode_EdgeBias(t *testing.T) {
+	val := biasStruct{
+		IntField:   -128,
+		RangeField: 123_456,
+		UintField:  20,
+		FloatField: 1.5,
+		PlainField: 7,
+	}
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+
+	filled := biasStruct{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+
+	encoded, err = Encode(&val, WithEdgeBias(true))
+	require.NoError(t, err)
+
+	filled = biasStruct{}
+	Fill(&filled, encoded, WithEdgeBias(true))
+	assert.Equal(t, val, filled)
+}
+
+func TestFingerprint_EdgeBias(t *testing.T) {
+	assert.NotEqual(t, Fingerprint(&biasStruct{}), Fingerprint(&biasStruct{}, WithEdgeBias(true)))
+}
+
+type badBiasTag struct {
+	IntField int `fuzz-bias:"middle"`
+}
+
+func TestFill_BadBiasTag(t *testing.T) {
+	assert.PanicsWithError(t, `fuzzhelper.badBiasTag.IntField has invalid fuzz-bias "middle", must be "edges"`, func() {
+		Fill(&badBiasTag{}, []byte{1})
+	})
+}
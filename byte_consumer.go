@@ -7,7 +7,11 @@ package fuzzhelper
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
 	"unicode/utf8"
 	"unsafe"
 )
@@ -24,26 +28,110 @@ const (
 
 type byteConsumer struct {
 	bytes []byte
+
+	// Tracks how many of the input bytes have been consumed. Generated
+	// bytes are tracked separately.
+	inputLeft     int
+	inputConsumed int
+	generated     int
+
+	// When expansion is enabled, once bytes have been used up we continue
+	// to generate up to expansionLeft bytes from expansion
+	expansion     *rand.Rand
+	expansionLeft int
+
+	// When bit packing is enabled, bools and small choices draw
+	// individual bits from a reservoir byte. A new reservoir byte is
+	// consumed, in the normal byte order, whenever the reservoir is empty.
+	packBits bool
+	bits     byte
+	bitsLeft int
+
+	// Tracks the reservoir byte being written to by pushBits, counted
+	// back from the last byte pushed
+	pushBitsLeft  int
+	pushBitsAfter int
+
+	// When split is enabled, control values are consumed from the end of
+	// the bytes. When pushing, control values are collected in tail,
+	// with the most recently pushed value first.
+	split bool
+	tail  []byte
 }
 
 func newByteConsumer(bytes []byte) *byteConsumer {
 	return &byteConsumer{
-		bytes: bytes,
+		// Clipped so that expansion, and pushing, never write to the
+		// caller's bytes beyond the slice
+		bytes:     slices.Clip(bytes),
+		inputLeft: len(bytes),
 	}
 }
 
 func (c *byteConsumer) getRawBytes() []byte {
-	return c.bytes
+	if len(c.tail) == 0 {
+		return c.bytes
+	}
+	return append(slices.Clone(c.bytes), c.tail...)
+}
+
+// Returns the input bytes which have not been consumed yet
+func (c *byteConsumer) remaining() []byte {
+	return c.bytes[:c.inputLeft]
 }
 
 func (c *byteConsumer) len() int {
-	return len(c.bytes)
+	if c.bitsLeft > 0 {
+		// Unused bits in the reservoir count as a byte
+		return len(c.bytes) + c.expansionLeft + 1
+	}
+	return len(c.bytes) + c.expansionLeft
+}
+
+// Control values, i.e. bools, choices and lengths, will be consumed from the
+// end of the bytes while all other values are consumed from the start. This
+// mirrors the layout used by libFuzzer's FuzzedDataProvider.
+func (c *byteConsumer) enableSplit() {
+	c.split = true
+}
+
+// Bools and choices between at most maxPackedChoices options will draw bits
+// from a reservoir byte, rather than consuming whole bytes.
+func (c *byteConsumer) enableBitPacking() {
+	c.packBits = true
+}
+
+// Once the bytes have been consumed, generate up to limit further bytes from
+// a pseudo-random generator seeded from the bytes themselves.
+func (c *byteConsumer) enableExpansion(limit int) {
+	seed := fnv.New64a()
+	seed.Write(c.bytes)
+
+	c.expansion = rand.New(rand.NewPCG(seed.Sum64(), uint64(len(c.bytes))))
+	c.expansionLeft = max(limit, 0)
+}
+
+func (c *byteConsumer) expand(size int) {
+	needed := min(size-len(c.bytes), c.expansionLeft)
+	for range needed {
+		c.bytes = append(c.bytes, byte(c.expansion.Uint32()))
+	}
+	c.expansionLeft -= needed
+	c.generated += needed
 }
 
 func (c *byteConsumer) consume(size int) []byte {
+	if len(c.bytes) < size && c.expansionLeft > 0 {
+		c.expand(size)
+	}
+
 	consumed := make([]byte, size)
 	copy(consumed, c.bytes)
 
+	inputUsed := min(size, c.inputLeft)
+	c.inputLeft -= inputUsed
+	c.inputConsumed += inputUsed
+
 	if len(c.bytes) <= size {
 		c.bytes = c.bytes[:0]
 	} else {
@@ -52,9 +140,51 @@ func (c *byteConsumer) consume(size int) []byte {
 	return consumed
 }
 
-// Test only
+// Consumes a control value. These are consumed from the end of the bytes
+// if split is enabled.
+func (c *byteConsumer) consumeControl(size int) []byte {
+	if !c.split {
+		return c.consume(size)
+	}
+
+	if len(c.bytes) < size && c.expansionLeft > 0 {
+		c.expand(size)
+	}
+
+	start := max(len(c.bytes)-size, 0)
+	consumed := make([]byte, size)
+	copy(consumed, c.bytes[start:])
+
+	// Any generated bytes sit after the input bytes, and are consumed
+	// first
+	generated := len(c.bytes) - c.inputLeft
+	inputUsed := max(len(c.bytes)-start-generated, 0)
+	c.inputLeft -= inputUsed
+	c.inputConsumed += inputUsed
+
+	c.bytes = c.bytes[:start]
+	return consumed
+}
+
+// Used by Encode and tests
 func (c *byteConsumer) pushBytes(bytes []byte) {
 	c.bytes = append(c.bytes, bytes...)
+	c.inputLeft += len(bytes)
+	if !c.split {
+		c.pushBitsAfter += len(bytes)
+	}
+}
+
+// Used by Encode and tests. If split is enabled the raw bytes must be taken
+// from getRawBytes and consumed by a new byteConsumer.
+func (c *byteConsumer) pushControl(bytes []byte) {
+	if !c.split {
+		c.pushBytes(bytes)
+		return
+	}
+
+	c.tail = append(slices.Clone(bytes), c.tail...)
+	c.pushBitsAfter += len(bytes)
 }
 
 func (c *byteConsumer) singleByte() byte {
@@ -68,18 +198,27 @@ func (c *byteConsumer) pushByte(b byte) {
 }
 
 func (c *byteConsumer) consumeUint64(bytes uintptr) uint64 {
+	return decodeUint64(c.consume, bytes)
+}
+
+// Consumes a control value, see enableSplit
+func (c *byteConsumer) consumeControlUint64(bytes uintptr) uint64 {
+	return decodeUint64(c.consumeControl, bytes)
+}
+
+func decodeUint64(consume func(int) []byte, bytes uintptr) uint64 {
 	switch bytes {
 	case 8:
-		dest := c.consume(8)
+		dest := consume(8)
 		return binary.LittleEndian.Uint64(dest)
 	case 4:
-		dest := c.consume(4)
+		dest := consume(4)
 		return uint64(binary.LittleEndian.Uint32(dest))
 	case 2:
-		dest := c.consume(2)
+		dest := consume(2)
 		return uint64(binary.LittleEndian.Uint16(dest))
 	case 1:
-		dest := c.consume(1)
+		dest := consume(1)
 		return uint64(dest[0])
 	default:
 		panic(fmt.Sprintf("Must provided either 8, 4, 2, or 1 as bytes argument. %d found.", bytes))
@@ -87,18 +226,27 @@ func (c *byteConsumer) consumeUint64(bytes uintptr) uint64 {
 }
 
 func (c *byteConsumer) consumeInt64(bytes uintptr) int64 {
+	return decodeInt64(c.consume, bytes)
+}
+
+// Consumes a control value, see enableSplit
+func (c *byteConsumer) consumeControlInt64(bytes uintptr) int64 {
+	return decodeInt64(c.consumeControl, bytes)
+}
+
+func decodeInt64(consume func(int) []byte, bytes uintptr) int64 {
 	switch bytes {
 	case 8:
-		dest := c.consume(8)
+		dest := consume(8)
 		return int64(binary.LittleEndian.Uint64(dest))
 	case 4:
-		dest := c.consume(4)
+		dest := consume(4)
 		return int64(int32((binary.LittleEndian.Uint32(dest))))
 	case 2:
-		dest := c.consume(2)
+		dest := consume(2)
 		return int64(int16((binary.LittleEndian.Uint16(dest))))
 	case 1:
-		dest := c.consume(1)
+		dest := consume(1)
 		return int64(int8((dest[0])))
 	default:
 		panic(fmt.Sprintf("Must provided either 8, 4, 2, or 1 as bytes argument. %d found.", bytes))
@@ -125,7 +273,8 @@ func (c *byteConsumer) String(length int) string {
 	// Extract the valid runes from the bytes
 	//
 	// The way this is implemented right now will create strings which are
-	// randomly shorter than asked for. We may want to correct this.
+	// randomly shorter than asked for. See charsetString for strings with
+	// exactly length runes.
 	validRunes := []rune{}
 	for len(bytes) > 0 {
 		r, l := utf8.DecodeRune(bytes)
@@ -139,45 +288,141 @@ func (c *byteConsumer) String(length int) string {
 }
 
 func (c *byteConsumer) consumeBool() bool {
-	bytes := c.consume(1)
+	if c.packBits {
+		return c.consumeBits(1) == 1
+	}
+
+	bytes := c.consumeControl(1)
 	return bytes[0]%2 == 1
 }
 
-// test only
+// Choices between more options than this always consume whole bytes
+const maxPackedChoices = 16
+
+// Returns the number of bits needed to choose between n options
+func bitsForChoice(n int) int {
+	return bits.Len(uint(n - 1))
+}
+
+// Consumes either size bytes, or a few bits if bit packing is enabled, to
+// choose between n options. Returns the index of the chosen option.
+func (c *byteConsumer) consumeChoice(n int, size uintptr) int {
+	if c.packBits && n <= maxPackedChoices {
+		// Taking the bits modulo n would favour the first options
+		// when n isn't a power of 2, so bits which are too large are
+		// rejected and drawn again. Once the bytes run out the bits
+		// are all 0, which ends the loop.
+		for {
+			if val := int(c.consumeBits(bitsForChoice(n))); val < n {
+				return val
+			}
+		}
+	}
+
+	val := c.consumeControlUint64(size)
+	return int(val % uint64(n))
+}
+
+// Consumes size bytes as an offset into a range of span values. Taking the
+// value modulo span would favour the smallest offsets when span doesn't
+// divide the values size bytes can hold, so values beyond the last whole
+// multiple of span are rejected and drawn again. Once the bytes run out the
+// values are all 0, which ends the loop.
+func (c *byteConsumer) consumeOffset(size uintptr, span uint64) uint64 {
+	maxVal := uint64(math.MaxUint64) >> (64 - 8*size)
+	if span == 0 || span-1 >= maxVal {
+		// Every value is an offset into the range
+		return c.consumeUint64(size)
+	}
+
+	excess := (maxVal%span + 1) % span
+	for {
+		if val := c.consumeUint64(size); val <= maxVal-excess {
+			return val
+		}
+	}
+}
+
+// Consumes a control byte which selects whether a special value, such as an
+// edge value of a biased number or a previously allocated pointer, should be
+// used. Returns true, and a value used to select from the special values,
+// if one should be used.
+func (c *byteConsumer) consumeSelector() (selector int, ok bool) {
+	b := c.consumeControl(1)[0]
+	if b%2 == 0 {
+		return 0, false
+	}
+	return int(b >> 1), true
+}
+
+// Returns n bits from the bit reservoir, least significant bit first
+func (c *byteConsumer) consumeBits(n int) uint64 {
+	val := uint64(0)
+	for i := range n {
+		if c.bitsLeft == 0 {
+			c.bits = c.consumeControl(1)[0]
+			c.bitsLeft = 8
+		}
+		val |= uint64(c.bits&1) << i
+		c.bits >>= 1
+		c.bitsLeft--
+	}
+	return val
+}
+
+// Used by Encode and tests
 func (c *byteConsumer) pushUint64(value uint64, bytes uintptr) {
+	c.pushBytes(encodeUint64(value, bytes))
+}
+
+// Used by Encode and tests
+func (c *byteConsumer) pushControlUint64(value uint64, bytes uintptr) {
+	c.pushControl(encodeUint64(value, bytes))
+}
+
+func encodeUint64(value uint64, bytes uintptr) []byte {
 	switch bytes {
 	case 8:
 		bytes := make([]byte, 8)
 		binary.LittleEndian.PutUint64(bytes, value)
-		c.pushBytes(bytes)
+		return bytes
 	case 4:
 		bytes := make([]byte, 4)
 		binary.LittleEndian.PutUint32(bytes, uint32(value))
-		c.pushBytes(bytes)
+		return bytes
 	case 2:
 		bytes := make([]byte, 2)
 		binary.LittleEndian.PutUint16(bytes, uint16(value))
-		c.pushBytes(bytes)
+		return bytes
 	case 1:
 		bytes := make([]byte, 1)
 		bytes[0] = byte(value)
-		c.pushBytes(bytes)
+		return bytes
 	default:
 		panic(fmt.Sprintf("Must provided either 8, 4, 2, or 1 as bytes argument. %d found.", bytes))
 	}
 }
 
-// test only
+// Used by Encode and tests
 func (c *byteConsumer) pushInt64(value int64, bytes uintptr) {
+	c.pushBytes(encodeInt64(value, bytes))
+}
+
+// Used by Encode and tests
+func (c *byteConsumer) pushControlInt64(value int64, bytes uintptr) {
+	c.pushControl(encodeInt64(value, bytes))
+}
+
+func encodeInt64(value int64, bytes uintptr) []byte {
 	switch bytes {
 	case 8:
-		c.pushUint64(uint64(value), bytes)
+		return encodeUint64(uint64(value), bytes)
 	case 4:
-		c.pushUint64(uint64(int32(value)), bytes)
+		return encodeUint64(uint64(int32(value)), bytes)
 	case 2:
-		c.pushUint64(uint64(int16(value)), bytes)
+		return encodeUint64(uint64(int16(value)), bytes)
 	case 1:
-		c.pushUint64(uint64(int8(value)), bytes)
+		return encodeUint64(uint64(int8(value)), bytes)
 	default:
 		panic(fmt.Sprintf("Must provided either 8, 4, 2, or 1 as bytes argument. %d found.", bytes))
 	}
@@ -202,10 +447,64 @@ func (c *byteConsumer) pushString(str string) {
 	c.pushBytes([]byte(str))
 }
 
+// Used by Encode and tests
 func (c *byteConsumer) pushBool(value bool) {
+	if c.packBits {
+		if value {
+			c.pushBits(1, 1)
+		} else {
+			c.pushBits(0, 1)
+		}
+		return
+	}
+
 	if value {
-		c.pushBytes([]byte{1})
+		c.pushControl([]byte{1})
 	} else {
-		c.pushBytes([]byte{0})
+		c.pushControl([]byte{0})
+	}
+}
+
+// Used by Encode and tests
+func (c *byteConsumer) pushChoice(index, n int, size uintptr) {
+	if c.packBits && n <= maxPackedChoices {
+		c.pushBits(uint64(index), bitsForChoice(n))
+		return
+	}
+
+	c.pushControlUint64(uint64(index), size)
+}
+
+// The largest index which can be selected by a control byte
+const maxSelector = 127
+
+// Used by Encode, selects no special value, see consumeSelector
+func (c *byteConsumer) pushNoSelector() {
+	c.pushControl([]byte{0})
+}
+
+// Used by Encode and tests, selects the special value at index, see
+// consumeSelector
+func (c *byteConsumer) pushSelector(index int) {
+	c.pushControl([]byte{byte(index<<1 | 1)})
+}
+
+// Writes n bits to the current reservoir byte, pushing a new reservoir byte
+// whenever the current one is full. Bits must be pushed before the
+// reservoir byte is consumed.
+func (c *byteConsumer) pushBits(value uint64, n int) {
+	for i := range n {
+		if c.pushBitsLeft == 0 {
+			c.pushControl([]byte{0})
+			c.pushBitsAfter = 0
+			c.pushBitsLeft = 8
+		}
+		bit := byte((value>>i)&1) << (8 - c.pushBitsLeft)
+		if c.split {
+			c.tail[c.pushBitsAfter] |= bit
+		} else {
+			c.bytes[len(c.bytes)-1-c.pushBitsAfter] |= bit
+		}
+		c.pushBitsLeft--
 	}
 }
@@ -123,3 +123,128 @@ func TestByteConsumer_Combined(t *testing.T) {
 	assert.Equal(t, uint32(100_000), uint32(consumer.consumeUint64(4)))
 	assert.Equal(t, 0, consumer.len())
 }
+
+func TestByteConsumer_Expansion(t *testing.T) {
+	consumer := newByteConsumer([]byte{1, 2, 3})
+	consumer.enableExpansion(5)
+	assert.Equal(t, 8, consumer.len())
+
+	// The input bytes are consumed first, followed by generated bytes
+	consumed := consumer.consume(4)
+	assert.Equal(t, []byte{1, 2, 3}, consumed[:3])
+	assert.Equal(t, 4, consumer.len())
+
+	// Generated bytes stop at the limit, then we get zeroes
+	consumed = consumer.consume(6)
+	assert.Equal(t, 0, consumer.len())
+	assert.Equal(t, []byte{0, 0}, consumed[4:])
+
+	// Generated bytes are deterministic for the same input
+	first := newByteConsumer([]byte{1, 2, 3})
+	first.enableExpansion(100)
+	second := newByteConsumer([]byte{1, 2, 3})
+	second.enableExpansion(100)
+	assert.Equal(t, first.consume(103), second.consume(103))
+}
+
+func TestByteConsumer_PackedBits(t *testing.T) {
+	consumer := newByteConsumer([]byte{})
+	consumer.enableBitPacking()
+
+	// Eight bools fit in one byte
+	bools := []bool{true, false, false, true, true, false, true, true}
+	for _, b := range bools {
+		consumer.pushBool(b)
+	}
+	assert.Equal(t, []byte{0b11011001}, consumer.getRawBytes())
+
+	// A choice between 5 options needs 3 bits, the third choice starts
+	// a new reservoir byte. Whole bytes pushed while the reservoir is
+	// partly used follow the reservoir byte.
+	consumer.pushChoice(4, 5, bytesForNative)
+	consumer.pushUint64(99, bytesFor8)
+	consumer.pushChoice(3, 5, bytesForNative)
+	consumer.pushChoice(4, 5, bytesForNative)
+	// Large choices still use whole bytes
+	consumer.pushChoice(20, 30, bytesFor8)
+	assert.Equal(t, []byte{0b11011001, 0b00011100, 99, 0b1, 20}, consumer.getRawBytes())
+
+	for _, b := range bools {
+		assert.Equal(t, b, consumer.consumeBool())
+	}
+	assert.Equal(t, 4, consumer.consumeChoice(5, bytesForNative))
+	assert.Equal(t, uint64(99), consumer.consumeUint64(bytesFor8))
+	assert.Equal(t, 3, consumer.consumeChoice(5, bytesForNative))
+	assert.Equal(t, 4, consumer.consumeChoice(5, bytesForNative))
+	assert.Equal(t, 20, consumer.consumeChoice(30, bytesFor8))
+	// The unused bits of the last reservoir byte are still available
+	assert.Equal(t, 1, consumer.len())
+}
+
+func TestByteConsumer_PackedChoiceUnbiased(t *testing.T) {
+	// A choice between 3 options draws 2 bits, 0b11 is rejected and 2
+	// more bits are drawn
+	consumer := newByteConsumer([]byte{0b00_10_11_01})
+	consumer.enableBitPacking()
+	assert.Equal(t, 1, consumer.consumeChoice(3, bytesForNative))
+	assert.Equal(t, 2, consumer.consumeChoice(3, bytesForNative))
+	assert.Equal(t, 0, consumer.consumeChoice(3, bytesForNative))
+
+	// Every option is chosen by the same number of bytes, except for
+	// 0b11111111 which runs out of bits and chooses 0
+	counts := make([]int, 3)
+	for b := range 256 {
+		consumer := newByteConsumer([]byte{byte(b)})
+		consumer.enableBitPacking()
+		counts[consumer.consumeChoice(3, bytesForNative)]++
+	}
+	assert.Equal(t, []int{86, 85, 85}, counts)
+}
+
+func TestByteConsumer_Split(t *testing.T) {
+	pushed := newByteConsumer([]byte{})
+	pushed.enableSplit()
+
+	pushed.pushControlUint64(1, bytesFor16)
+	pushed.pushUint64(2, bytesFor16)
+	pushed.pushBool(true)
+	pushed.pushBytes([]byte{3, 4})
+	pushed.pushControlUint64(5, bytesFor8)
+
+	// Control values are in reverse order at the end, each keeps its own
+	// byte order
+	raw := pushed.getRawBytes()
+	assert.Equal(t, []byte{2, 0, 3, 4, 5, 1, 1, 0}, raw)
+
+	consumer := newByteConsumer(raw)
+	consumer.enableSplit()
+	assert.Equal(t, uint64(1), consumer.consumeControlUint64(bytesFor16))
+	assert.Equal(t, uint64(2), consumer.consumeUint64(bytesFor16))
+	assert.True(t, consumer.consumeBool())
+	assert.Equal(t, []byte{3, 4}, consumer.consume(2))
+	assert.Equal(t, uint64(5), consumer.consumeControlUint64(bytesFor8))
+	assert.Equal(t, 0, consumer.len())
+	assert.Equal(t, 8, consumer.inputConsumed)
+}
+
+func TestByteConsumer_SplitPackedBits(t *testing.T) {
+	pushed := newByteConsumer([]byte{})
+	pushed.enableSplit()
+	pushed.enableBitPacking()
+
+	pushed.pushBool(true)
+	pushed.pushControlUint64(7, bytesFor8)
+	pushed.pushBool(true)
+	pushed.pushUint64(9, bytesFor8)
+
+	raw := pushed.getRawBytes()
+	assert.Equal(t, []byte{9, 7, 0b11}, raw)
+
+	consumer := newByteConsumer(raw)
+	consumer.enableSplit()
+	consumer.enableBitPacking()
+	assert.True(t, consumer.consumeBool())
+	assert.Equal(t, uint64(7), consumer.consumeControlUint64(bytesFor8))
+	assert.True(t, consumer.consumeBool())
+	assert.Equal(t, uint64(9), consumer.consumeUint64(bytesFor8))
+}
@@ -0,0 +1,116 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Slices and arrays of bytes are copied straight from the input, rather than
+// being filled one element at a time. The bytes consumed are the same either
+// way, so this doesn't change the values filled.
+//
+// fuzz-bytes-range:"4,64" sets the length of a []byte, just as
+// fuzz-slice-range does for other slices.
+//
+// fuzz-rest:"true" fills a []byte with all of the input bytes remaining
+// when it is filled, which suits the payload of a protocol message. No
+// length is consumed and no bytes are generated by expansion, see
+// WithExpansionLimit, so values filled after it, in the order set by
+// WithTraversal, are left empty.
+//
+// An untagged []byte grows one byte at a time, like any other unbounded
+// slice, and bytes with a fuzz-uint-range, fuzz-uint-method or edge bias are
+// filled one at a time as well. An unbounded slice consumes no length, and
+// its growth is interleaved with the other values filled in the order set
+// by WithTraversal, so its bytes are not a single run of the input. Copying
+// it in one chunk needs a length to be consumed, changing the values filled
+// from every existing corpus input with a []byte, so it is only done with
+// WithByteChunks.
+
+// WithByteChunks copies every untagged []byte straight from the input, as
+// if it had a fuzz-bytes-range:"0,20" tag. A length is consumed, and then
+// that many bytes are copied in one chunk. This is much faster than growing
+// large byte slices one byte at a time, but changes the values filled from
+// existing corpus inputs.
+func WithByteChunks(enabled bool) Option {
+	return func(config *fillConfig) {
+		config.byteChunks = enabled
+	}
+}
+
+// Checks that the tags for byte slices can be applied to field, and sets the
+// slice range from fuzz-bytes-range
+func newBytesTags(structVal reflect.Value, field reflect.StructField, prefix string, t *fuzzTags) {
+	bytesRange := newLengthTagRange(structVal, field, prefix+"bytes-range")
+	t.rest = newBoolTag(structVal, field, prefix+"rest")
+	if !bytesRange.uintRange.wasSet && !t.rest.wasSet {
+		return
+	}
+
+	if !isByteSlice(containerType(field.Type)) {
+		panic(fmt.Errorf("%s.%s has %sbytes-range or %srest tags, but %s is not a []byte", structVal.Type(), field.Name, prefix, prefix, field.Type))
+	}
+
+	if t.sliceRange.uintRange.wasSet || (bytesRange.uintRange.wasSet && t.rest.enabled) {
+		panic(fmt.Errorf("%s.%s has more than one of %sslice-range, %sbytes-range and %srest", structVal.Type(), field.Name, prefix, prefix, prefix))
+	}
+
+	if bytesRange.uintRange.wasSet {
+		t.sliceRange = bytesRange
+	}
+}
+
+// Returns true if typ is a slice of bytes
+func isByteSlice(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+}
+
+// Returns true if value is a slice or array of bytes
+func isBytes(value reflect.Value) bool {
+	kind := value.Kind()
+	return (kind == reflect.Slice || kind == reflect.Array) && value.Type().Elem().Kind() == reflect.Uint8
+}
+
+// Returns true if the bytes of value, a slice or array of bytes, can be
+// copied straight from the input
+func (config fillConfig) copiesBytes(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	if !value.CanSet() || (value.Kind() == reflect.Slice && config.atMaxDepth(tags, path)) {
+		return false
+	}
+	if tags.rest.enabled {
+		return true
+	}
+
+	if value.Kind() == reflect.Slice && !tags.sliceRange.uintRange.wasSet && !config.byteChunks {
+		// Unbounded slices grow one element at a time
+		return false
+	}
+
+	elemTags := tags.elementTags()
+	return !elemTags.uintRange.wasSet && !elemTags.uintValues.wasSet && !config.biased(elemTags)
+}
+
+// Copies bytes from the input into value, a slice or array of bytes. As
+// with other slices, the bytes are appended to a slice.
+func setBytes(value reflect.Value, bytes []byte) {
+	if value.Kind() == reflect.Slice {
+		value.Set(reflect.AppendSlice(value, reflect.ValueOf(bytes).Convert(value.Type())))
+		return
+	}
+	reflect.Copy(value, reflect.ValueOf(bytes))
+}
+
+// Returns the length range of a copied []byte. An untagged []byte, copied
+// with WithByteChunks, has the default length range.
+func bytesLength(tags fuzzTags) lengthTagRange {
+	if tags.sliceRange.uintRange.wasSet {
+		return tags.sliceRange
+	}
+	return lengthTagRange{
+		uintRange: uintTagRange{
+			wasSet:  true,
+			uintMin: defaultLengthMin,
+			uintMax: defaultLengthMax,
+		},
+	}
+}
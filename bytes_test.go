@@ -0,0 +1,193 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bytesStruct struct {
+	Header  [4]byte
+	Key     []byte  `fuzz-bytes-range:"2,3"`
+	Flags   []uint8 `fuzz-slice-range:"1,1" fuzz-sorted:"true"`
+	Payload []byte  `fuzz-rest:"true"`
+}
+
+func TestFill_Bytes(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// Header
+	c.pushBytes([]byte{1, 2, 3, 4})
+	// Key
+	c.pushUint64(1, bytesForNative)
+	c.pushBytes([]byte{5, 6, 7})
+	// Flags
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{8})
+	// Payload
+	c.pushBytes([]byte{9, 10, 11, 12, 13})
+
+	val := bytesStruct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.Equal(t, bytesStruct{
+		Header:  [4]byte{1, 2, 3, 4},
+		Key:     []byte{5, 6, 7},
+		Flags:   []uint8{8},
+		Payload: []byte{9, 10, 11, 12, 13},
+	}, val)
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	assert.Equal(t, c.getRawBytes(), encoded)
+}
+
+// Copying the bytes consumes the same bytes as filling them one at a time
+func TestFill_BytesMatchElements(t *testing.T) {
+	type copiedStruct struct {
+		Array [3]byte
+		Slice []byte `fuzz-slice-range:"0,5"`
+	}
+	type elementsStruct struct {
+		Array [3]byte `fuzz-uint-range:"0,255"`
+		Slice []byte  `fuzz-slice-range:"0,5" fuzz-uint-range:"0,255"`
+	}
+
+	for _, bytes := range [][]byte{
+		{},
+		{1, 2},
+		{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	} {
+		copied := copiedStruct{}
+		Fill(&copied, bytes)
+		elements := elementsStruct{}
+		Fill(&elements, bytes)
+		assert.Equal(t, copiedStruct(elements), copied)
+	}
+}
+
+func TestFill_BytesRestWithSplitLayout(t *testing.T) {
+	type testStruct struct {
+		Count   uint8 `fuzz-uint-range:"1,3"`
+		Flag    bool
+		Payload []byte `fuzz-rest:"true"`
+	}
+
+	val := testStruct{Count: 2, Flag: true, Payload: []byte{5, 6, 7}}
+	encoded, err := Encode(&val, WithLayout(SplitLayout))
+	require.NoError(t, err)
+
+	filled := testStruct{}
+	Fill(&filled, encoded, WithLayout(SplitLayout))
+	assert.Equal(t, val, filled)
+}
+
+func TestFill_BytesRestIgnoresExpansion(t *testing.T) {
+	type testStruct struct {
+		Payload []byte `fuzz-rest:"true"`
+	}
+
+	val := testStruct{}
+	Fill(&val, []byte{1, 2}, WithExhaustion(PRNGOnExhaustion), WithExpansionLimit(100))
+	assert.Equal(t, []byte{1, 2}, val.Payload)
+}
+
+func TestEncode_BytesRest(t *testing.T) {
+	type testStruct struct {
+		Payload []byte `fuzz-rest:"true"`
+		After   uint8
+	}
+
+	_, err := Encode(&testStruct{Payload: []byte{1, 2}, After: 3})
+	assert.EqualError(t, err, "cannot encode *(testStruct).Payload ([]uint8): it takes the rest of the input, but is followed by values which would be lost")
+
+	encoded, err := Encode(&testStruct{Payload: []byte{1, 2}})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2}, encoded)
+}
+
+func TestFill_ByteChunks(t *testing.T) {
+	type testStruct struct {
+		Data  []byte
+		After uint8
+	}
+
+	c := newByteConsumer([]byte{})
+	// Data
+	c.pushUint64(3, bytesForNative)
+	c.pushBytes([]byte{1, 2, 3})
+	// After
+	c.pushBytes([]byte{9})
+
+	// The untagged []byte is copied in one chunk
+	val := testStruct{}
+	Fill(&val, c.getRawBytes(), WithByteChunks(true))
+	assert.Equal(t, testStruct{Data: []byte{1, 2, 3}, After: 9}, val)
+
+	encoded, err := Encode(&val, WithByteChunks(true))
+	require.NoError(t, err)
+	assert.Equal(t, c.getRawBytes(), encoded)
+
+	// Without the option it grows one byte at a time
+	val = testStruct{}
+	Fill(&val, c.getRawBytes())
+	assert.NotEqual(t, []byte{1, 2, 3}, val.Data)
+}
+
+// With WithByteChunks an untagged []byte is filled just as one tagged with
+// the default length range
+func TestFill_ByteChunksMatchBytesRange(t *testing.T) {
+	type untaggedStruct struct {
+		Data []byte
+	}
+	type taggedStruct struct {
+		Data []byte `fuzz-bytes-range:"0,20"`
+	}
+
+	for _, bytes := range [][]byte{
+		{},
+		{1, 2},
+		{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	} {
+		untagged := untaggedStruct{}
+		Fill(&untagged, bytes, WithByteChunks(true))
+		tagged := taggedStruct{}
+		Fill(&tagged, bytes)
+		assert.Equal(t, untaggedStruct(tagged), untagged)
+	}
+}
+
+func ExampleWithByteChunks() {
+	type testStruct struct {
+		Data []byte
+	}
+
+	Describe(&testStruct{}, WithByteChunks(true))
+	// Output:*(testStruct).Data ([]uint8)
+	//	range: 0 to 20
+	//	copied in one chunk
+}
+
+type badBytesRangeTag struct {
+	IntsField []int `fuzz-bytes-range:"1,2"`
+}
+
+type badRestTag struct {
+	ArrayField [4]byte `fuzz-rest:"true"`
+}
+
+type badBytesRangeAndSliceRange struct {
+	BytesField []byte `fuzz-slice-range:"1,2" fuzz-bytes-range:"1,2"`
+}
+
+func TestFill_BadBytesTags(t *testing.T) {
+	assert.PanicsWithError(t, "fuzzhelper.badBytesRangeTag.IntsField has fuzz-bytes-range or fuzz-rest tags, but []int is not a []byte", func() {
+		Fill(&badBytesRangeTag{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badRestTag.ArrayField has fuzz-bytes-range or fuzz-rest tags, but [4]uint8 is not a []byte", func() {
+		Fill(&badRestTag{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badBytesRangeAndSliceRange.BytesField has more than one of fuzz-slice-range, fuzz-bytes-range and fuzz-rest", func() {
+		Fill(&badBytesRangeAndSliceRange{}, []byte{1})
+	})
+}
@@ -0,0 +1,245 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"reflect"
+	"regexp/syntax"
+	"unicode/utf8"
+)
+
+// By default a string is made from the bytes consumed for its length,
+// dropping any bytes which aren't valid UTF-8, so strings are often shorter
+// than their length. The string tags change how strings are made.
+//
+// fuzz-charset picks each rune of a string from a set of runes, so the
+// string has exactly as many runes as its length. The set is one of
+//
+//	ascii       runes 0 to 127
+//	printable   runes ' ' to '~'
+//	alnum       0-9, A-Z and a-z
+//	hex         0-9 and a-f
+//	identifier  alnum and '_', not starting with a digit
+//	unicode     every valid rune
+//
+// or a regular expression character class such as fuzz-charset:"[a-z0-9_]".
+// Each rune consumes 1, 2 or 4 bytes, the fewest which can pick any rune in
+// the set.
+//
+// fuzz-string-exact:"true" makes a string with exactly as many runes as its
+// length, picked from every valid rune. It is the same as
+// fuzz-charset:"unicode".
+//
+// fuzz-string-raw:"true" makes a string straight from the bytes consumed for
+// its length, which may not be valid UTF-8.
+
+// The surrogate halves, which are not valid runes
+var surrogates = interval[int64]{lo: 0xD800, hi: 0xDFFF}
+
+var namedCharsets = map[string][]interval[int64]{
+	"ascii":     {{lo: 0, hi: utf8.RuneSelf - 1}},
+	"printable": {{lo: ' ', hi: '~'}},
+	"alnum":     {{lo: '0', hi: '9'}, {lo: 'A', hi: 'Z'}, {lo: 'a', hi: 'z'}},
+	"hex":       {{lo: '0', hi: '9'}, {lo: 'a', hi: 'f'}},
+	"identifier": {
+		{lo: '0', hi: '9'}, {lo: 'A', hi: 'Z'}, {lo: '_', hi: '_'}, {lo: 'a', hi: 'z'},
+	},
+	"unicode": {{lo: 0, hi: surrogates.lo - 1}, {lo: surrogates.hi + 1, hi: utf8.MaxRune}},
+}
+
+// The runes an identifier may start with
+var identifierStart = []interval[int64]{{lo: 'A', hi: 'Z'}, {lo: '_', hi: '_'}, {lo: 'a', hi: 'z'}}
+
+type charsetTag struct {
+	wasSet bool
+	name   string
+	// The runes which may start a string
+	first []interval[int64]
+	// The runes which may follow the first rune
+	runes []interval[int64]
+	// Strings are made from raw bytes, see fuzz-string-raw
+	raw bool
+}
+
+func newCharsetTag(structVal reflect.Value, field reflect.StructField, prefix string) charsetTag {
+	name, charsetSet := field.Tag.Lookup(prefix + "charset")
+	exact := newBoolTag(structVal, field, prefix+"string-exact")
+	raw := newBoolTag(structVal, field, prefix+"string-raw")
+	if !charsetSet && !exact.wasSet && !raw.wasSet {
+		return charsetTag{}
+	}
+
+	if !containsString(field.Type) {
+		panic(fmt.Errorf("%s.%s has %scharset, %sstring-exact or %sstring-raw tags, but %s does not contain a string", structVal.Type(), field.Name, prefix, prefix, prefix, field.Type))
+	}
+
+	if raw.enabled && (charsetSet || exact.enabled) {
+		panic(fmt.Errorf("%s.%s has %sstring-raw, which can't be used with %scharset or %sstring-exact", structVal.Type(), field.Name, prefix, prefix, prefix))
+	}
+
+	if raw.enabled {
+		return charsetTag{
+			wasSet: true,
+			raw:    true,
+		}
+	}
+
+	if !charsetSet {
+		if !exact.enabled {
+			return charsetTag{}
+		}
+		name = "unicode"
+	}
+
+	runes, ok := namedCharsets[name]
+	if !ok {
+		runes, ok = parseCharClass(name)
+	}
+	if !ok {
+		panic(fmt.Errorf("%s.%s has invalid %scharset %q, must be ascii, printable, alnum, hex, identifier, unicode or a character class like [a-z]", structVal.Type(), field.Name, prefix, name))
+	}
+
+	first := runes
+	if name == "identifier" {
+		first = identifierStart
+	}
+	return charsetTag{
+		wasSet: true,
+		name:   name,
+		first:  first,
+		runes:  runes,
+	}
+}
+
+// Parses a regular expression character class, e.g. [a-z0-9_], into the
+// valid runes it matches. Returns false if class is not a character class
+// matching at least one valid rune.
+func parseCharClass(class string) ([]interval[int64], bool) {
+	re, err := syntax.Parse(class, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+
+	pairs := []rune{}
+	switch re.Op {
+	case syntax.OpCharClass:
+		pairs = re.Rune
+	case syntax.OpLiteral:
+		// A class with a single rune, e.g. [a], is parsed as a literal
+		if len(re.Rune) != 1 || re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		pairs = []rune{re.Rune[0], re.Rune[0]}
+	default:
+		return nil, false
+	}
+
+	runes := classRunes(pairs)
+	return runes, len(runes) > 0
+}
+
+// Returns the valid runes in pairs, the ranges of a syntax.Regexp character
+// class
+func classRunes(pairs []rune) []interval[int64] {
+	runes := []interval[int64]{}
+	for i := 0; i < len(pairs); i += 2 {
+		lo, hi := int64(pairs[i]), int64(pairs[i+1])
+		// Leave out the surrogate halves
+		if lo < surrogates.lo {
+			runes = append(runes, interval[int64]{lo: lo, hi: min(hi, surrogates.lo-1)})
+		}
+		if hi > surrogates.hi {
+			runes = append(runes, interval[int64]{lo: max(lo, surrogates.hi+1), hi: hi})
+		}
+	}
+	return runes
+}
+
+// Returns true if typ is a string, or contains strings
+func containsString(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return containsString(typ.Elem())
+	case reflect.Map:
+		return containsString(typ.Key()) || containsString(typ.Elem())
+	default:
+		return false
+	}
+}
+
+// Returns the runes which may be at index i of a string
+func (t charsetTag) runesAt(i int) []interval[int64] {
+	if i == 0 {
+		return t.first
+	}
+	return t.runes
+}
+
+// Returns the number of bytes consumed to pick a rune from runes
+func runeSize(runes []interval[int64]) uintptr {
+	span := intervalsSpan(runes)
+	switch {
+	case span <= 1<<8:
+		return bytesFor8
+	case span <= 1<<16:
+		return bytesFor16
+	default:
+		return bytesFor32
+	}
+}
+
+// Returns a string of length runes, each picked from the charset
+func (c *byteConsumer) charsetString(length int, charset charsetTag) string {
+	runes := make([]rune, length)
+	for i := range runes {
+		set := charset.runesAt(i)
+		runes[i] = rune(intervalsFitOffset(set, c.consumeUint64(runeSize(set))))
+	}
+	return string(runes)
+}
+
+// Returns a string made from length bytes, which may not be valid UTF-8
+func (c *byteConsumer) rawString(length int) string {
+	return string(c.consume(length))
+}
+
+// Pushes the runes of str, the inverse of charsetString. Returns the index
+// of the first rune not in the charset, or -1.
+func (c *byteConsumer) pushCharsetString(str string, charset charsetTag) int {
+	for i, r := range []rune(str) {
+		set := charset.runesAt(i)
+		offset, ok := intervalsOffset(set, int64(r))
+		if !ok {
+			return i
+		}
+		c.pushUint64(offset, runeSize(set))
+	}
+	return -1
+}
+
+// Returns the string following str, counting through the strings of runes
+// from the charset with at most maxLen runes. Returns false if str is the
+// last of these strings, or contains runes not in the charset.
+func (t charsetTag) nextString(str string, maxLen int) (string, bool) {
+	runes := []rune(str)
+	for i := len(runes) - 1; i >= 0; i-- {
+		set := t.runesAt(i)
+		offset, ok := intervalsOffset(set, int64(runes[i]))
+		if !ok {
+			return "", false
+		}
+		if offset+1 < intervalsSpan(set) {
+			runes[i] = rune(intervalsFitOffset(set, offset+1))
+			return string(runes), true
+		}
+		// Carry
+		runes[i] = rune(set[0].lo)
+	}
+
+	if len(runes) >= maxLen {
+		return "", false
+	}
+	runes = append(runes, rune(t.runesAt(len(runes))[0].lo))
+	return string(runes), true
+}
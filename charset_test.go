@@ -0,0 +1,150 @@
+package fuzzhelper
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type charsetStruct struct {
+	HexField        string   `fuzz-string-range:"4,4" fuzz-charset:"hex"`
+	IdentField      string   `fuzz-string-range:"3,3" fuzz-charset:"identifier"`
+	ClassField      string   `fuzz-string-range:"2,2" fuzz-charset:"[xyz]"`
+	ExactField      string   `fuzz-string-range:"2,2" fuzz-string-exact:"true"`
+	RawField        string   `fuzz-string-range:"3,3" fuzz-string-raw:"true"`
+	PrintablesField []string `fuzz-slice-range:"1,1" fuzz-string-range:"1,1" fuzz-charset:"printable"`
+}
+
+func TestFill_Charset(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// HexField, picks wrap around the 16 hex digits
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{0, 9, 10, 16 + 15})
+	// IdentField, digits can't start an identifier
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{0, 0, 36})
+	// ClassField
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{2, 1})
+	// ExactField, picks skip the surrogate halves
+	c.pushUint64(0, bytesForNative)
+	c.pushUint64('a', bytesFor32)
+	c.pushUint64(0xD800, bytesFor32)
+	// RawField
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{0xff, 'a', 0xfe})
+	// PrintablesField
+	c.pushUint64(0, bytesForNative)
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{'!' - ' '})
+
+	val := charsetStruct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.Equal(t, charsetStruct{
+		HexField:        "09af",
+		IdentField:      "A0_",
+		ClassField:      "zy",
+		ExactField:      "a",
+		RawField:        "\xffa\xfe",
+		PrintablesField: []string{"!"},
+	}, val)
+	assert.Equal(t, 2, utf8.RuneCountInString(val.ExactField))
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	filled := charsetStruct{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+}
+
+func TestEncode_Charset(t *testing.T) {
+	_, err := Encode(&charsetStruct{HexField: "00g0"})
+	assert.EqualError(t, err, `cannot encode *(charsetStruct).HexField (string): rune 2 of string "00g0" is not in charset hex`)
+
+	_, err = Encode(&charsetStruct{HexField: "0000", IdentField: "9ab"})
+	assert.EqualError(t, err, `cannot encode *(charsetStruct).IdentField (string): rune 0 of string "9ab" is not in charset identifier`)
+}
+
+func TestParseCharClass(t *testing.T) {
+	runes, ok := parseCharClass("[a-c_]")
+	assert.True(t, ok)
+	assert.Equal(t, []interval[int64]{{lo: '_', hi: '_'}, {lo: 'a', hi: 'c'}}, runes)
+
+	runes, ok = parseCharClass("[a]")
+	assert.True(t, ok)
+	assert.Equal(t, []interval[int64]{{lo: 'a', hi: 'a'}}, runes)
+
+	// Negated classes leave out the surrogate halves
+	runes, ok = parseCharClass("[^a]")
+	assert.True(t, ok)
+	assert.Equal(t, []interval[int64]{{lo: 0, hi: 'a' - 1}, {lo: 'a' + 1, hi: 0xD7FF}, {lo: 0xE000, hi: utf8.MaxRune}}, runes)
+
+	for _, bad := range []string{"abc", "[a-", ""} {
+		_, ok = parseCharClass(bad)
+		assert.False(t, ok, bad)
+	}
+}
+
+func TestCharsetNextString(t *testing.T) {
+	hex := charsetTag{wasSet: true, name: "hex", first: namedCharsets["hex"], runes: namedCharsets["hex"]}
+	for _, testCase := range []struct {
+		str      string
+		expected string
+		ok       bool
+	}{
+		{"", "0", true},
+		{"0", "1", true},
+		{"9", "a", true},
+		{"0f", "10", true},
+		{"f", "00", true},
+		{"ff", "", false},
+		{"g", "", false},
+	} {
+		next, ok := hex.nextString(testCase.str, 2)
+		assert.Equal(t, testCase.ok, ok, testCase.str)
+		assert.Equal(t, testCase.expected, next, testCase.str)
+	}
+}
+
+func TestFill_CharsetWithSemicolon(t *testing.T) {
+	type testStruct struct {
+		// The ";" is part of the class, not a tag level
+		StringsField []string `fuzz-slice-range:"1,1" fuzz-string-range:"3,3" fuzz-charset:"[;:]"`
+	}
+
+	c := newByteConsumer([]byte{})
+	c.pushUint64(0, bytesForNative)
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{0, 1, 2})
+
+	val := testStruct{}
+	Fill(&val, c.getRawBytes())
+	assert.Equal(t, []string{":;:"}, val.StringsField)
+}
+
+type badCharsetName struct {
+	StringField string `fuzz-charset:"emoji"`
+}
+
+type badCharsetNotString struct {
+	IntField int `fuzz-string-exact:"true"`
+}
+
+type badCharsetRaw struct {
+	StringField string `fuzz-charset:"hex" fuzz-string-raw:"true"`
+}
+
+func TestFill_BadCharsetTags(t *testing.T) {
+	assert.PanicsWithError(t, `fuzzhelper.badCharsetName.StringField has invalid fuzz-charset "emoji", must be ascii, printable, alnum, hex, identifier, unicode or a character class like [a-z]`, func() {
+		Fill(&badCharsetName{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badCharsetNotString.IntField has fuzz-charset, fuzz-string-exact or fuzz-string-raw tags, but int does not contain a string", func() {
+		Fill(&badCharsetNotString{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badCharsetRaw.StringField has fuzz-string-raw, which can't be used with fuzz-charset or fuzz-string-exact", func() {
+		Fill(&badCharsetRaw{}, []byte{1})
+	})
+}
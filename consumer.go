@@ -0,0 +1,111 @@
+package fuzzhelper
+
+import "fmt"
+
+// WithBitPacking enables bit packing. Bools, and choices between at most 16
+// method or interface options, draw individual bits from a shared byte
+// instead of consuming whole bytes. A struct with eight bool fields then
+// consumes a single byte, and mutating one bit of the input flips exactly
+// one flag. Every option of a choice is equally likely, bits which would
+// choose beyond the last option are drawn again.
+//
+// FillPrefix and Consumer.Remaining only return whole bytes, so the unused
+// bits of a partly drawn byte are not returned. The next value is filled
+// from a fresh byte.
+func WithBitPacking(enabled bool) Option {
+	return func(config *fillConfig) {
+		config.packBits = enabled
+	}
+}
+
+// A Consumer draws values directly from a fuzzer's input bytes. It is
+// useful when a fuzz test needs to make decisions while it runs, e.g.
+// choosing the next operation to apply, rather than filling a single value
+// up front. Values can be drawn one at a time and mixed freely with calls
+// to Fill.
+//
+// Once the bytes are used up every value is drawn as if from zeroed bytes,
+// unless the PRNGOnExhaustion policy is used.
+type Consumer struct {
+	c      *byteConsumer
+	config fillConfig
+}
+
+// NewConsumer returns a Consumer which draws values from bytes, configured
+// by opts. With WithBitPacking each call to Bool, and each Choose between at
+// most 16 options, draws only the bits it needs. With SplitLayout, Bool and
+// Choose consume bytes from the end of the input.
+func NewConsumer(bytes []byte, opts ...Option) *Consumer {
+	config := newFillConfig(opts)
+	c := newByteConsumer(bytes)
+	if config.exhaustion == PRNGOnExhaustion {
+		c.enableExpansion(config.expansionLimit)
+	}
+	if config.packBits {
+		c.enableBitPacking()
+	}
+	if config.layout == SplitLayout {
+		c.enableSplit()
+	}
+	return &Consumer{
+		c:      c,
+		config: config,
+	}
+}
+
+// Len returns the number of bytes which can still be consumed.
+func (c *Consumer) Len() int {
+	return c.c.len()
+}
+
+// Remaining returns the input bytes which have not been consumed.
+func (c *Consumer) Remaining() []byte {
+	return c.c.remaining()
+}
+
+// Bool consumes a single bit with bit packing enabled, otherwise a byte.
+func (c *Consumer) Bool() bool {
+	return c.c.consumeBool()
+}
+
+// Choose returns an index in [0, n), consuming bytes in the same way as
+// Fill does when choosing between n method options. Choose panics if n is
+// not positive.
+func (c *Consumer) Choose(n int) int {
+	if n <= 0 {
+		panic(fmt.Errorf("cannot choose between %d options", n))
+	}
+	return c.c.consumeChoice(n, c.config.format.choiceSize(n))
+}
+
+// Int64 consumes 8 bytes.
+func (c *Consumer) Int64() int64 {
+	return c.c.consumeInt64(bytesFor64)
+}
+
+// Uint64 consumes 8 bytes.
+func (c *Consumer) Uint64() uint64 {
+	return c.c.consumeUint64(bytesFor64)
+}
+
+// Float64 consumes 8 bytes.
+func (c *Consumer) Float64() float64 {
+	return c.c.consumeFloat64(bytesFor64)
+}
+
+// Bytes consumes exactly n bytes.
+func (c *Consumer) Bytes(n int) []byte {
+	return c.c.consume(n)
+}
+
+// String consumes length bytes and returns the valid UTF-8 runes found in
+// them.
+func (c *Consumer) String(length int) string {
+	return c.c.String(length)
+}
+
+// Fill fills root from the bytes which have not been consumed yet, in the
+// same way as the Fill function.
+func (c *Consumer) Fill(root any) {
+	fill(root, c.c, c.config)
+}
@@ -0,0 +1,83 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flagsStruct struct {
+	A, B, C, D, E, F, G, H bool
+	Mode                   string `fuzz-string-method:"Modes"`
+	Count                  uint8
+	I                      bool
+}
+
+func (s *flagsStruct) Modes() []string {
+	return []string{"off", "on", "auto"}
+}
+
+func TestFill_BitPacking(t *testing.T) {
+	// The first eight flags fill a reservoir byte, Mode needs 2 bits from
+	// a new reservoir byte and I uses the next bit of that byte
+	input := []byte{0b10000101, 0b110, 42}
+
+	val := flagsStruct{}
+	Fill(&val, input, WithBitPacking(true))
+	assert.Equal(t, flagsStruct{A: true, C: true, H: true, Mode: "auto", Count: 42, I: true}, val)
+
+	encoded, err := Encode(&val, WithBitPacking(true))
+	require.NoError(t, err)
+	assert.Equal(t, input, encoded)
+}
+
+func TestFill_BitPacking_SingleBitFlip(t *testing.T) {
+	val := flagsStruct{A: true, E: true, Mode: "on", Count: 7}
+	encoded, err := Encode(&val, WithBitPacking(true))
+	require.NoError(t, err)
+
+	// Flipping each bit of the first byte flips exactly one flag
+	for bit := range 8 {
+		mutated := append([]byte{}, encoded...)
+		mutated[0] ^= 1 << bit
+
+		filled := flagsStruct{}
+		Fill(&filled, mutated, WithBitPacking(true))
+
+		expected := val
+		flags := []*bool{&expected.A, &expected.B, &expected.C, &expected.D, &expected.E, &expected.F, &expected.G, &expected.H}
+		*flags[bit] = !*flags[bit]
+		assert.Equal(t, expected, filled)
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	input := []byte{0b1101, 7, 0, 0, 0, 0, 0, 0, 0, 'a', 'b', 0, 0, 0, 0, 0, 0, 9, 10}
+	c := NewConsumer(input, WithBitPacking(true), WithFormat(CompactFormat))
+
+	assert.True(t, c.Bool())
+	assert.False(t, c.Bool())
+	// Three bits from the same byte
+	assert.Equal(t, 3, c.Choose(8))
+	assert.Equal(t, int64(7), c.Int64())
+	assert.Equal(t, "ab", c.String(2))
+
+	// Fill continues from the same bytes, and the same reservoir byte
+	val := struct {
+		Flag  bool
+		Value uint64
+	}{}
+	c.Fill(&val)
+	assert.False(t, val.Flag)
+	assert.Equal(t, uint64(0x0a09)<<48, val.Value)
+
+	assert.Equal(t, 0, len(c.Remaining()))
+	// Values are drawn from zeroed bytes once the input is used up
+	assert.Equal(t, uint64(0), c.Uint64())
+}
+
+func TestConsumer_ChooseInvalid(t *testing.T) {
+	c := NewConsumer([]byte{1})
+	assert.Panics(t, func() { c.Choose(0) })
+}
@@ -0,0 +1,69 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithMaxDepth limits how deeply nested the structs filled by Fill can be.
+// The fields of the root struct are at depth 1, the fields of a struct
+// pointed to by one of those fields at depth 2 and so on. Individual fields
+// can be given their own limit with a fuzz-max-depth:"3" tag, which takes
+// precedence over this option. The default is 0, no limit.
+//
+// At the maximum depth pointers are left nil and slices are left empty,
+// regardless of their fuzz tags. Interfaces are filled with one of the
+// options returned by their fuzz-leaf-method, if they have one, otherwise
+// they are left nil. No bytes are consumed for values left nil or empty.
+//
+// This allows recursive types, like trees and expressions, to be filled
+// with predictable shapes rather than until the bytes run out.
+func WithMaxDepth(depth int) Option {
+	return func(config *fillConfig) {
+		config.maxDepth = max(depth, 0)
+	}
+}
+
+type maxDepthTag struct {
+	wasSet bool
+	depth  int
+}
+
+func newMaxDepthTag(structVal reflect.Value, field reflect.StructField, tag string) maxDepthTag {
+	valStr, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return maxDepthTag{}
+	}
+
+	depth, err := strconv.Atoi(valStr)
+	if err != nil || depth <= 0 {
+		panic(fmt.Errorf("%s.%s has invalid %s %q, must be a positive integer", structVal.Type(), field.Name, tag, valStr))
+	}
+
+	return maxDepthTag{
+		wasSet: true,
+		depth:  depth,
+	}
+}
+
+// Returns true if a value with tags, found at path, is at the maximum depth
+func (config fillConfig) atMaxDepth(tags fuzzTags, path valuePath) bool {
+	maxDepth := config.maxDepth
+	if tags.maxDepth.wasSet {
+		maxDepth = tags.maxDepth.depth
+	}
+	return maxDepth > 0 && path.depth() >= maxDepth
+}
+
+// Returns the number of structs containing the value at the end of the path
+func (p valuePath) depth() int {
+	depth := 0
+	for i, val := range p.values {
+		if val.Kind() == reflect.Struct && strings.HasPrefix(p.names[i], "(") {
+			depth++
+		}
+	}
+	return depth
+}
@@ -0,0 +1,123 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type depthNode struct {
+	Value    int8
+	Left     *depthNode
+	Right    *depthNode
+	Children []depthNode `fuzz-slice-range:"1,2"`
+}
+
+func (n *depthNode) depth() int {
+	if n == nil {
+		return 0
+	}
+	depth := max(n.Left.depth(), n.Right.depth())
+	for i := range n.Children {
+		depth = max(depth, n.Children[i].depth())
+	}
+	return depth + 1
+}
+
+func TestFill_MaxDepth(t *testing.T) {
+	bytes := make([]byte, 10_000)
+	for i := range bytes {
+		bytes[i] = byte(i)
+	}
+
+	for maxDepth := 1; maxDepth <= 4; maxDepth++ {
+		val := depthNode{}
+		rest := FillPrefix(&val, bytes, WithMaxDepth(maxDepth))
+
+		assert.Equal(t, maxDepth, val.depth())
+		// The tree is complete, so there are bytes left over
+		assert.NotEmpty(t, rest)
+	}
+}
+
+type depthExpr interface {
+	eval() int
+}
+
+type depthLiteral struct {
+	Value int8
+}
+
+func (l *depthLiteral) eval() int {
+	return int(l.Value)
+}
+
+type depthSum struct {
+	Left  depthExpr `fuzz-interface-method:"Exprs" fuzz-leaf-method:"Leaves" fuzz-max-depth:"3"`
+	Right depthExpr `fuzz-interface-method:"Exprs" fuzz-leaf-method:"Leaves" fuzz-max-depth:"3"`
+}
+
+func (s *depthSum) eval() int {
+	return s.Left.eval() + s.Right.eval()
+}
+
+func (s *depthSum) Exprs() []depthExpr {
+	return []depthExpr{&depthSum{}, &depthLiteral{}}
+}
+
+func (s *depthSum) Leaves() []depthExpr {
+	return []depthExpr{&depthLiteral{}}
+}
+
+func TestFill_MaxDepthLeafOptions(t *testing.T) {
+	// Always choose a sum, until the leaf options are used
+	val := depthSum{}
+	Fill(&val, make([]byte, 1000))
+
+	// Depth 1 and 2 are sums, and depth 3 is made of literals
+	sum := val.Left.(*depthSum)
+	assert.IsType(t, &depthSum{}, sum.Left)
+	assert.IsType(t, &depthLiteral{}, sum.Left.(*depthSum).Left)
+	assert.IsType(t, &depthLiteral{}, sum.Left.(*depthSum).Right)
+	assert.Equal(t, 0, val.eval())
+}
+
+func TestEncode_MaxDepth(t *testing.T) {
+	val := depthSum{
+		Left: &depthLiteral{Value: 1},
+		Right: &depthSum{
+			Left: &depthLiteral{Value: 2},
+			Right: &depthSum{
+				Left:  &depthLiteral{Value: 3},
+				Right: &depthLiteral{Value: 4},
+			},
+		},
+	}
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+
+	filled := depthSum{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+
+	// Sums can't be used at the maximum depth
+	val.Right.(*depthSum).Right.(*depthSum).Right = &depthSum{}
+	_, err = Encode(&val)
+	assert.EqualError(t, err, "cannot encode *(depthSum).Right(ifc)(*depthSum).Right(ifc)(*depthSum).Right (interface): type *fuzzhelper.depthSum is not one of the leaf options at the maximum depth")
+
+	node := depthNode{Left: &depthNode{Left: &depthNode{}}, Children: []depthNode{{}}}
+	_, err = Encode(&node, WithMaxDepth(2))
+	assert.EqualError(t, err, "cannot encode *(depthNode).Left(*depthNode).Left (*depthNode): it must be nil or empty at the maximum depth")
+}
+
+type badMaxDepthTag struct {
+	PointerField *badMaxDepthTag `fuzz-max-depth:"0"`
+}
+
+func TestFill_BadMaxDepthTag(t *testing.T) {
+	assert.PanicsWithError(t, `fuzzhelper.badMaxDepthTag.PointerField has invalid fuzz-max-depth "0", must be a positive integer`, func() {
+		Fill(&badMaxDepthTag{}, []byte{1})
+	})
+}
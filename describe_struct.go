@@ -11,10 +11,14 @@ import (
 var _ valueVisitor = &describeVisitor{}
 
 type describeVisitor struct {
+	config fillConfig
 }
 
-func Describe(root any) {
-	visitRoot(&describeVisitor{}, root, newByteConsumer([]byte{1, 2, 3}))
+// Describe prints a description of each of the values Fill would fill in
+// root, and how they would be filled. Options which change the values Fill
+// produces, such as WithNilPercent, are included in the description.
+func Describe(root any, opts ...Option) {
+	visitRoot(&describeVisitor{config: newFillConfig(opts)}, root, newByteConsumer([]byte{1, 2, 3}), BreadthFirst)
 }
 
 func shortenString(s string) string {
@@ -42,6 +46,26 @@ func isExported(name string) bool {
 	return unicode.IsUpper(firstRune)
 }
 
+func weightsDescription(tags fuzzTags) {
+	if tags.weights.wasSet {
+		fmt.Fprintf(os.Stdout, "\tweights: %s\n", tags.weights)
+	}
+}
+
+func elementsDescription(tags fuzzTags) {
+	if tags.permutation.enabled {
+		fmt.Fprintf(os.Stdout, "\tpermutation\n")
+	}
+	if tags.unique.enabled {
+		fmt.Fprintf(os.Stdout, "\tunique\n")
+	}
+	if tags.sortMethod.wasSet {
+		fmt.Fprintf(os.Stdout, "\tsorted (%s)\n", tags.sortMethod.methodName)
+	} else if tags.sorted.enabled {
+		fmt.Fprintf(os.Stdout, "\tsorted\n")
+	}
+}
+
 func introDescription(value reflect.Value, tags fuzzTags, path valuePath) {
 	fmt.Fprintf(os.Stdout, "%s\n", path.pathString(value))
 
@@ -77,10 +101,11 @@ func (v *describeVisitor) visitInt(value reflect.Value, c *byteConsumer, tags fu
 	// First check if there is a list of valid string values
 	if tags.intValues.wasSet {
 		fmt.Fprintf(os.Stdout, "\tmethod (%s): %s\n", tags.intValues.methodName, methodValuesString(tags.intValues.value))
+		weightsDescription(tags)
 		return
 	}
 
-	fmt.Fprintf(os.Stdout, "\trange min: %d max: %d\n", tags.intRange.intMin, tags.intRange.intMax)
+	fmt.Fprintf(os.Stdout, "\trange: %s\n", intervalsString(tags.intRange.parts()))
 
 }
 
@@ -95,10 +120,11 @@ func (v *describeVisitor) visitUint(value reflect.Value, c *byteConsumer, tags f
 	// First check if there is a list of valid uint values
 	if tags.uintValues.wasSet {
 		fmt.Fprintf(os.Stdout, "\tmethod (%s): %s\n", tags.uintValues.methodName, methodValuesString(tags.uintValues.value))
+		weightsDescription(tags)
 		return
 	}
 
-	fmt.Fprintf(os.Stdout, "\trange min: %d max: %d\n", tags.uintRange.uintMin, tags.uintRange.uintMax)
+	fmt.Fprintf(os.Stdout, "\trange: %s\n", intervalsString(tags.uintRange.parts()))
 }
 
 func (v *describeVisitor) visitUintptr(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
@@ -116,10 +142,11 @@ func (v *describeVisitor) visitFloat(value reflect.Value, c *byteConsumer, tags
 	// First check if there is a list of valid float values
 	if tags.floatValues.wasSet {
 		fmt.Fprintf(os.Stdout, "\tmethod (%s): %s\n", tags.floatValues.methodName, methodValuesString(tags.floatValues.value))
+		weightsDescription(tags)
 		return
 	}
 
-	fmt.Fprintf(os.Stdout, "\trange min: %g max: %g\n", tags.floatRange.floatMin, tags.floatRange.floatMax)
+	fmt.Fprintf(os.Stdout, "\trange: %s\n", intervalsString(tags.floatRange.parts()))
 }
 
 func (v *describeVisitor) visitComplex(value reflect.Value, tags fuzzTags, path valuePath) {
@@ -129,13 +156,29 @@ func (v *describeVisitor) visitComplex(value reflect.Value, tags fuzzTags, path
 
 func (v *describeVisitor) visitArray(value reflect.Value, tags fuzzTags, path valuePath) {
 	introDescription(value, tags, path)
+	elementsDescription(tags)
 }
 
-func (v *describeVisitor) visitPointer(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+func (v *describeVisitor) visitElements(value reflect.Value, tags fuzzTags, path valuePath) {
+}
+
+func (v *describeVisitor) visitPointer(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
 	//introDescription(value, tags, path)
 
 	if !value.CanSet() {
-		return
+		return true
+	}
+
+	percent := v.config.nilChance(tags)
+	aliased := v.config.aliased(tags)
+	if percent > 0 || aliased {
+		introDescription(value, tags, path)
+	}
+	if percent > 0 {
+		fmt.Fprintf(os.Stdout, "\tnil: %d%%\n", percent)
+	}
+	if aliased {
+		fmt.Fprintf(os.Stdout, "\taliased: may reuse a previously allocated %s\n", typeString(value.Type()))
 	}
 
 	// allocate a value for value to point to
@@ -143,6 +186,7 @@ func (v *describeVisitor) visitPointer(value reflect.Value, c *byteConsumer, tag
 	vType := pType.Elem()
 	newVal := reflect.New(vType)
 	value.Set(newVal)
+	return true
 }
 
 func (v *describeVisitor) visitSlice(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) (from, to int) {
@@ -155,7 +199,8 @@ func (v *describeVisitor) visitSlice(value reflect.Value, c *byteConsumer, tags
 
 	introDescription(value, tags, path)
 
-	fmt.Fprintf(os.Stdout, "\trange min: %d max: %d\n", tags.sliceRange.uintRange.uintMin, tags.sliceRange.uintRange.uintMax)
+	fmt.Fprintf(os.Stdout, "\trange: %s\n", intervalsString(tags.sliceRange.uintRange.parts()))
+	elementsDescription(tags)
 
 	if !value.CanSet() {
 		return 0, 0
@@ -167,10 +212,38 @@ func (v *describeVisitor) visitSlice(value reflect.Value, c *byteConsumer, tags
 	return 0, 1
 }
 
+func (v *describeVisitor) visitBytes(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
+	switch {
+	case tags.rest.enabled:
+		introDescription(value, tags, path)
+		fmt.Fprintf(os.Stdout, "\trest of the input\n")
+	case value.Kind() == reflect.Slice && !tags.sliceRange.uintRange.wasSet && v.config.copiesBytes(value, tags, path):
+		// An untagged []byte copied in one chunk, see WithByteChunks
+		length := bytesLength(tags)
+		introDescription(value, tags, path)
+		fmt.Fprintf(os.Stdout, "\trange: %s\n", intervalsString(length.uintRange.parts()))
+		fmt.Fprintf(os.Stdout, "\tcopied in one chunk\n")
+	default:
+		return false
+	}
+
+	elementsDescription(tags)
+	return true
+}
+
 func (v *describeVisitor) visitMap(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) int {
 	introDescription(value, tags, path)
 
-	fmt.Fprintf(os.Stdout, "\trange min: %d max: %d\n", tags.mapRange.uintRange.uintMin, tags.mapRange.uintRange.uintMax)
+	fmt.Fprintf(os.Stdout, "\trange: %s\n", intervalsString(tags.mapRange.uintRange.parts()))
+
+	if v.config.distinctKeys(tags) {
+		fmt.Fprintf(os.Stdout, "\tdistinct keys\n")
+		key := reflect.New(value.Type().Key()).Elem()
+		minLen := tags.mapRange.uintRange.uintMin
+		if domain, ok := keyDomain(key, tags.mapKeyTags()); ok && domain < minLen {
+			fmt.Fprintf(os.Stdout, "\tkey domain: %d values, smaller than the minimum length %d\n", domain, minLen)
+		}
+	}
 
 	if !value.CanSet() {
 		return 0
@@ -185,6 +258,13 @@ func (v *describeVisitor) visitMap(value reflect.Value, c *byteConsumer, tags fu
 	return mapLen
 }
 
+func (v *describeVisitor) visitMapKey(mapValue, key reflect.Value, tags fuzzTags) {
+}
+
+func (v *describeVisitor) mapEntry(mapValue reflect.Value, index int) (key, val reflect.Value) {
+	return newMapEntry(mapValue)
+}
+
 func (v *describeVisitor) visitChan(value reflect.Value, tags fuzzTags, path valuePath) {
 	notSupported(value, path)
 }
@@ -194,7 +274,22 @@ func (v *describeVisitor) visitFunc(value reflect.Value, tags fuzzTags, path val
 }
 
 func (v *describeVisitor) visitInterface(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
-	notSupported(value, path)
+	if !value.CanSet() || !tags.interfaceValues.wasSet {
+		notSupported(value, path)
+		return false
+	}
+
+	introDescription(value, tags, path)
+
+	types := []string{}
+	for _, option := range tags.interfaceValues.value {
+		types = append(types, reflect.TypeOf(option).String())
+	}
+	fmt.Fprintf(os.Stdout, "\tmethod (%s): %s\n", tags.interfaceValues.methodName, methodValuesString(types))
+	weightsDescription(tags)
+	if percent := v.config.nilChance(tags); percent > 0 {
+		fmt.Fprintf(os.Stdout, "\tnil: %d%%\n", percent)
+	}
 	return false
 }
 
@@ -209,10 +304,22 @@ func (v *describeVisitor) visitString(value reflect.Value, c *byteConsumer, tags
 	// First check if there is a list of valid string values
 	if tags.stringValues.wasSet {
 		fmt.Fprintf(os.Stdout, "\tmethod (%s): %s\n", tags.stringValues.methodName, methodValuesString(tags.stringValues.value))
+		weightsDescription(tags)
 		return
 	}
 
-	fmt.Fprintf(os.Stdout, "\trange min: %d max: %d\n", tags.stringRange.uintRange.uintMin, tags.stringRange.uintRange.uintMax)
+	if tags.regex.wasSet {
+		fmt.Fprintf(os.Stdout, "\tregex: %s\n", tags.regex.pattern)
+		return
+	}
+
+	fmt.Fprintf(os.Stdout, "\trange: %s\n", intervalsString(tags.stringRange.uintRange.parts()))
+	switch {
+	case tags.charset.raw:
+		fmt.Fprintf(os.Stdout, "\traw bytes, may not be valid UTF-8\n")
+	case tags.charset.wasSet:
+		fmt.Fprintf(os.Stdout, "\tcharset: %s\n", tags.charset.name)
+	}
 }
 
 func (v *describeVisitor) visitStruct(value reflect.Value, tags fuzzTags, path valuePath) bool {
@@ -237,6 +344,11 @@ func (v *describeVisitor) visitUnsafePointer(value reflect.Value, tags fuzzTags,
 	notSupported(value, path)
 }
 
+func (v *describeVisitor) visitExhausted(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	// Describe never consumes any bytes, so it should never be exhausted
+	return false
+}
+
 func notSupported(value reflect.Value, path valuePath) {
 	fmt.Fprintf(os.Stdout, "%s\n", path.pathString(value))
 	fmt.Fprintln(os.Stdout, "\tnot supported, will ignore")
@@ -9,7 +9,20 @@ func ExampleDescribe_stringRange() {
 
 	Describe(&testStruct{})
 	// Output:*(testStruct).StringField (string)
-	//	range min: 1 max: 5
+	//	range: 1 to 5
+}
+
+func ExampleDescribe_multiIntervalRange() {
+	type testStruct struct {
+		IntField   int16   `fuzz-int-range:"-10,0|100,200"`
+		FloatField float64 `fuzz-float-range:"0,0.5|2,2"`
+	}
+
+	Describe(&testStruct{})
+	// Output:*(testStruct).IntField (int16)
+	//	range: -10 to 0, 100 to 200
+	//*(testStruct).FloatField (float64)
+	//	range: 0 to 0.5, 2 to 2
 }
 
 type stringMethodStruct struct {
@@ -31,6 +44,21 @@ func ExampleDescribe_stringMethod() {
 	//	method (StringValues): [first second thi...
 }
 
+type weightedMethodStruct struct {
+	StringField string `fuzz-string-method:"StringValues" fuzz-weights:"6,2,1,1"`
+}
+
+func (s *weightedMethodStruct) StringValues() []string {
+	return []string{"push", "pop", "peek", "clear"}
+}
+
+func ExampleDescribe_weightedMethod() {
+	Describe(&weightedMethodStruct{})
+	// Output:*(weightedMethodStruct).StringField (string)
+	//	method (StringValues): [push pop peek cl...
+	//	weights: 6 (60.0%), 2 (20.0%), 1 (10.0%), 1 (10.0%)
+}
+
 func ExampleDescribe_unexportedString() {
 	type testStruct struct {
 		//lint:ignore U1000 This field is actually used via reflection
@@ -49,7 +77,7 @@ func ExampleDescribe_intRange() {
 
 	Describe(&testStruct{})
 	// Output:*(testStruct).IntField (int)
-	//	range min: -10 max: 50
+	//	range: -10 to 50
 }
 
 type intMethodStruct struct {
@@ -89,7 +117,7 @@ func ExampleDescribe_uintRange() {
 
 	Describe(&testStruct{})
 	// Output:*(testStruct).UintField (uint)
-	//	range min: 2 max: 7
+	//	range: 2 to 7
 }
 
 type uintMethodStruct struct {
@@ -129,7 +157,7 @@ func ExampleDescribe_floatRange() {
 
 	Describe(&testStruct{})
 	// Output:*(testStruct).FloatField (float64)
-	//	range min: 0.1 max: 0.5
+	//	range: 0.1 to 0.5
 }
 
 type float64MethodStruct struct {
@@ -169,9 +197,9 @@ func ExampleDescribe_sliceRange() {
 
 	Describe(&testStruct{})
 	// Output:*(testStruct).SliceField ([]float64)
-	//	range min: 3 max: 20
+	//	range: 3 to 20
 	//*(testStruct).SliceField[0] (float64)
-	//	range min: 0.2 max: 0.7
+	//	range: 0.2 to 0.7
 }
 
 func ExampleDescribe_array() {
@@ -182,13 +210,13 @@ func ExampleDescribe_array() {
 	Describe(&testStruct{})
 	// Output:*(testStruct).ArrayField ([4]uint64)
 	//*(testStruct).ArrayField[0] (uint64)
-	//	range min: 6 max: 100
+	//	range: 6 to 100
 	//*(testStruct).ArrayField[1] (uint64)
-	//	range min: 6 max: 100
+	//	range: 6 to 100
 	//*(testStruct).ArrayField[2] (uint64)
-	//	range min: 6 max: 100
+	//	range: 6 to 100
 	//*(testStruct).ArrayField[3] (uint64)
-	//	range min: 6 max: 100
+	//	range: 6 to 100
 }
 
 func ExampleDescribe_mapRange() {
@@ -198,11 +226,11 @@ func ExampleDescribe_mapRange() {
 
 	Describe(&testStruct{})
 	// Output:*(testStruct).MapField (map[int64]float64)
-	//	range min: 3 max: 20
+	//	range: 3 to 20
 	//*(testStruct).MapField[key] (int64)
-	//	range min: 5 max: 10
+	//	range: 5 to 10
 	//*(testStruct).MapField[value] (float64)
-	//	range min: 0.2 max: 0.7
+	//	range: 0.2 to 0.7
 }
 
 type parentStruct struct {
@@ -235,18 +263,18 @@ func ExampleDescribe_structInStruct() {
 	//	not exported, will ignore
 	//*(parentStruct).ValueChild(childStruct).BoolField (bool)
 	//*(parentStruct).ValueChild(childStruct).StringField (string)
-	//	range min: 0 max: 20
+	//	range: 0 to 20
 	//*(parentStruct).SliceChild ([]*childStruct)
-	//	range min: 0 max: 0
+	//	range: 0 to 0
 	//*(parentStruct).PointerChild(*childStruct).BoolField (bool)
 	//*(parentStruct).PointerChild(*childStruct).StringField (string)
-	//	range min: 0 max: 20
+	//	range: 0 to 20
 	//*(parentStruct).SliceChild[0](*childStruct).BoolField (bool)
 	//*(parentStruct).SliceChild[0](*childStruct).StringField (string)
-	//	range min: 0 max: 20
+	//	range: 0 to 20
 	//*(parentStruct).PointerPointerChild(**childStruct).BoolField (bool)
 	//*(parentStruct).PointerPointerChild(**childStruct).StringField (string)
-	//	range min: 0 max: 20
+	//	range: 0 to 20
 }
 
 func ExampleDescribe_unsupportedTypes() {
@@ -284,9 +312,9 @@ func ExampleDescribe_recursiveType() {
 
 	Describe(&testStruct{})
 	// Output:*(testStruct).IntField (int64)
-	//	range min: 0 max: 0
+	//	range: 0 to 0
 	//*(testStruct).StringField (string)
-	//	range min: 0 max: 20
+	//	range: 0 to 20
 	//*(testStruct).RecursiveField2 (*testStruct)
 	//	Recursion...
 	//*(testStruct).RecursiveField1 (**testStruct)
@@ -300,7 +328,61 @@ func ExampleDescribe_rootSlice() {
 
 	Describe(&[]testStruct{})
 	// Output:(*[]testStruct)
-	//	range min: 0 max: 0
+	//	range: 0 to 0
 	//*[0](testStruct).IntField (int64)
-	//	range min: 0 max: 0
+	//	range: 0 to 0
+}
+
+func ExampleDescribe_distinctMapKeys() {
+	type testStruct struct {
+		SetField map[uint8]struct{} `fuzz-map-range:"5,10" fuzz-uint-range:"1,3" fuzz-map-distinct:"true"`
+	}
+
+	Describe(&testStruct{})
+	// Output:*(testStruct).SetField (map[uint8]struct {})
+	//	range: 5 to 10
+	//	distinct keys
+	//	key domain: 3 values, smaller than the minimum length 5
+	//*(testStruct).SetField[key] (uint8)
+	//	range: 1 to 3
+}
+
+func ExampleDescribe_bytes() {
+	type testStruct struct {
+		KeyField     []byte `fuzz-bytes-range:"2,8"`
+		PayloadField []byte `fuzz-rest:"true"`
+	}
+
+	Describe(&testStruct{})
+	// Output:*(testStruct).KeyField ([]uint8)
+	//	range: 2 to 8
+	//*(testStruct).KeyField[0] (uint8)
+	//	range: 0 to 0
+	//*(testStruct).PayloadField ([]uint8)
+	//	rest of the input
+}
+
+func ExampleDescribe_charset() {
+	type testStruct struct {
+		IdentField string `fuzz-string-range:"1,8" fuzz-charset:"identifier"`
+		RawField   string `fuzz-string-range:"0,4" fuzz-string-raw:"true"`
+	}
+
+	Describe(&testStruct{})
+	// Output:*(testStruct).IdentField (string)
+	//	range: 1 to 8
+	//	charset: identifier
+	//*(testStruct).RawField (string)
+	//	range: 0 to 4
+	//	raw bytes, may not be valid UTF-8
+}
+
+func ExampleDescribe_regex() {
+	type testStruct struct {
+		VersionField string `fuzz-regex:"v[0-9]+\\.[0-9]+"`
+	}
+
+	Describe(&testStruct{})
+	// Output:*(testStruct).VersionField (string)
+	//	regex: v[0-9]+\.[0-9]+
 }
@@ -0,0 +1,221 @@
+package fuzzhelper
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"unicode/utf8"
+)
+
+// WithDistinctMapKeys makes every map reach the length chosen for it, by
+// replacing filled keys which are already in the map. Individual fields can
+// be given their own setting with a fuzz-map-distinct:"true" tag, which
+// takes precedence over this option.
+//
+// A key which is already in the map is replaced by the next value of its
+// key type, until a key which isn't in the map is found. Ints and uints
+// take the next value in their range, or of their type, wrapping around to
+// the smallest value. Method keys take the next option, bools the other
+// value, floats the next larger float in their range and strings the next
+// ASCII string, or string from their fuzz-charset, no longer than their
+// maximum length. No extra bytes are consumed.
+//
+// Keys of other kinds, such as structs, and strings with a fuzz-regex are
+// never replaced. Maps whose key type has fewer values than the length
+// chosen, e.g. a map[bool]int with 3 entries, can't reach their length. See
+// Describe.
+func WithDistinctMapKeys(enabled bool) Option {
+	return func(config *fillConfig) {
+		config.distinctMapKeys = enabled
+	}
+}
+
+// Returns true if the keys of a map with tags should be distinct
+func (config fillConfig) distinctKeys(tags fuzzTags) bool {
+	if tags.distinctKeys.wasSet {
+		return tags.distinctKeys.enabled
+	}
+	return config.distinctMapKeys
+}
+
+// Replaces key, filled with keyTags, until it is not a key of mapValue.
+func distinctMapKey(mapValue, key reflect.Value, keyTags fuzzTags) {
+	// At most one replacement per key already in the map is needed
+	for range mapValue.Len() {
+		if !mapValue.MapIndex(key).IsValid() || !nextValue(key, keyTags) {
+			return
+		}
+	}
+}
+
+// Sets key, a map key or a unique element, to the value following it.
+// Returns false if key can't be replaced.
+func nextValue(key reflect.Value, tags fuzzTags) bool {
+	if !key.CanSet() {
+		return false
+	}
+
+	switch key.Kind() {
+	case reflect.Bool:
+		key.SetBool(!key.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if tags.intValues.wasSet {
+			key.SetInt(nextOption(tags.intValues.value, key.Int()))
+			return true
+		}
+		r := keyIntRange(key, tags)
+		offset, _ := r.offset(key.Int())
+		key.SetInt(r.fitOffset(offset + 1))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if tags.uintValues.wasSet {
+			key.SetUint(nextOption(tags.uintValues.value, key.Uint()))
+			return true
+		}
+		r := keyUintRange(key, tags)
+		offset, _ := r.offset(key.Uint())
+		key.SetUint(r.fitOffset(offset + 1))
+
+	case reflect.Float32, reflect.Float64:
+		if tags.floatValues.wasSet {
+			key.SetFloat(nextOption(tags.floatValues.value, key.Float()))
+			return true
+		}
+		key.SetFloat(nextFloat(key, tags))
+
+	case reflect.String:
+		if tags.stringValues.wasSet {
+			key.SetString(nextOption(tags.stringValues.value, key.String()))
+			return true
+		}
+		if tags.regex.wasSet {
+			// The next string may not match the regex
+			return false
+		}
+		maxLen := int(tags.stringRange.uintRange.uintMax)
+		next, ok := nextString(key.String(), maxLen)
+		if tags.charset.wasSet && !tags.charset.raw {
+			next, ok = tags.charset.nextString(key.String(), maxLen)
+		}
+		if !ok {
+			return false
+		}
+		key.SetString(next)
+
+	default:
+		return false
+	}
+	return true
+}
+
+// Returns the option following value, wrapping around to the first option
+func nextOption[T comparable](options []T, value T) T {
+	index := slices.Index(options, value)
+	return options[(index+1)%len(options)]
+}
+
+// Returns the range of key, or the range of key's type if it has none
+func keyIntRange(key reflect.Value, tags fuzzTags) intTagRange {
+	if r := tags.intRange.within(key); r.valid() {
+		return r
+	}
+	typeMin := int64(-1) << (key.Type().Bits() - 1)
+	return newIntTagRangeFromIntervals([]interval[int64]{{lo: typeMin, hi: -(typeMin + 1)}})
+}
+
+// Returns the range of key, or the range of key's type if it has none
+func keyUintRange(key reflect.Value, tags fuzzTags) uintTagRange {
+	if r := tags.uintRange.within(key); r.valid() {
+		return r
+	}
+	typeMax := uint64(math.MaxUint64) >> (64 - key.Type().Bits())
+	return newUintTagRangeFromIntervals([]interval[uint64]{{lo: 0, hi: typeMax}})
+}
+
+// Returns the next larger float, in key's range if it has one, wrapping
+// around to the smallest float in the range
+func nextFloat(key reflect.Value, tags fuzzTags) float64 {
+	val := key.Float()
+	next := math.Nextafter(val, math.Inf(1))
+	if key.Kind() == reflect.Float32 {
+		next = float64(math.Nextafter32(float32(val), float32(math.Inf(1))))
+	}
+
+	r := tags.floatRange.within(key)
+	if !r.valid() || r.contains(next) {
+		return next
+	}
+	for _, i := range r.parts() {
+		if i.lo > val {
+			return i.lo
+		}
+	}
+	return r.floatMin
+}
+
+// Returns the string following str, counting through the strings of ASCII
+// characters with at most maxLen bytes. Returns false if str is the last of
+// these strings.
+func nextString(str string, maxLen int) (string, bool) {
+	b := []byte(str)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < utf8.RuneSelf-1 {
+			b[i]++
+			return string(b), true
+		}
+		// Carry, non-ASCII bytes are replaced as well
+		b[i] = 0
+	}
+
+	if len(b) >= maxLen {
+		return "", false
+	}
+	return string(make([]byte, len(b)+1)), true
+}
+
+// Returns the number of distinct keys which can be filled for a key with
+// tags. Returns false if this is too large to count.
+func keyDomain(key reflect.Value, tags fuzzTags) (uint64, bool) {
+	switch key.Kind() {
+	case reflect.Bool:
+		return 2, true
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if tags.intValues.wasSet {
+			return uint64(len(tags.intValues.value)), true
+		}
+		r := keyIntRange(key, tags)
+		span := r.span()
+		return span, span != 0
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if tags.uintValues.wasSet {
+			return uint64(len(tags.uintValues.value)), true
+		}
+		r := keyUintRange(key, tags)
+		span := r.span()
+		return span, span != 0
+
+	case reflect.Float32, reflect.Float64:
+		if tags.floatValues.wasSet {
+			return uint64(len(tags.floatValues.value)), true
+		}
+		return 0, false
+
+	case reflect.String:
+		if tags.stringValues.wasSet {
+			return uint64(len(tags.stringValues.value)), true
+		}
+		if tags.regex.wasSet {
+			return 0, false
+		}
+		if tags.stringRange.uintRange.uintMax == 0 {
+			return 1, true
+		}
+		return 0, false
+
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,54 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type distinctMapStruct struct {
+	BoolMap   map[bool]int8        `fuzz-map-range:"2,2" fuzz-map-distinct:"true"`
+	RangedMap map[uint8]struct{}   `fuzz-map-range:"5,5" fuzz-key-uint-range:"1,3" fuzz-map-distinct:"true"`
+	StringMap map[string]int8      `fuzz-map-range:"3,3" fuzz-string-range:"0,1"`
+	MethodMap map[int16]bool       `fuzz-map-range:"2,2" fuzz-key-int-method:"Keys"`
+	FloatMap  map[float32]struct{} `fuzz-map-range:"2,2" fuzz-float-range:"1,1|2,2"`
+}
+
+func (s *distinctMapStruct) Keys() []int16 {
+	return []int16{10, 20, 30}
+}
+
+func TestFill_DistinctMapKeysTag(t *testing.T) {
+	val := distinctMapStruct{}
+	Fill(&val, make([]byte, 200))
+
+	assert.Equal(t, map[bool]int8{false: 0, true: 0}, val.BoolMap)
+	// There are only 3 keys in the range, the map can't reach 5 entries
+	assert.Equal(t, map[uint8]struct{}{1: {}, 2: {}, 3: {}}, val.RangedMap)
+	// Without the tag keys overwrite each other
+	assert.Equal(t, map[string]int8{"": 0}, val.StringMap)
+	assert.Equal(t, map[int16]bool{10: false}, val.MethodMap)
+	assert.Equal(t, map[float32]struct{}{1: {}}, val.FloatMap)
+}
+
+func TestFill_DistinctMapKeysOption(t *testing.T) {
+	val := distinctMapStruct{}
+	Fill(&val, make([]byte, 200), WithDistinctMapKeys(true))
+
+	assert.Equal(t, map[string]int8{"": 0, "\x00": 0, "\x01": 0}, val.StringMap)
+	assert.Equal(t, map[int16]bool{10: false, 20: false}, val.MethodMap)
+	assert.Equal(t, map[float32]struct{}{1: {}, 2: {}}, val.FloatMap)
+}
+
+func TestNextString(t *testing.T) {
+	next, ok := nextString("a\x7f", 2)
+	assert.True(t, ok)
+	assert.Equal(t, "b\x00", next)
+
+	next, ok = nextString("\x7f", 2)
+	assert.True(t, ok)
+	assert.Equal(t, "\x00\x00", next)
+
+	_, ok = nextString("\x7f\x7f", 2)
+	assert.False(t, ok)
+}
@@ -0,0 +1,324 @@
+package fuzzhelper
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// The fuzz-unique, fuzz-sorted, fuzz-sort-method and fuzz-permutation tags
+// arrange the elements of a slice or array once they have been filled.
+//
+// fuzz-unique:"true" replaces each element equal to an earlier element with
+// the next value of its type, as for WithDistinctMapKeys, so no bytes are
+// wasted on duplicates. Elements which are not numbers, bools or strings are
+// never replaced.
+//
+// fuzz-sorted:"true" sorts the elements into ascending order. Numbers, bools
+// and strings have a natural order, other elements need a
+// fuzz-sort-method:"Less" tag naming a method, with the signature
+// func(a, b T) bool, on the struct containing the field. A sort method
+// implies fuzz-sorted.
+//
+// fuzz-permutation:"true" replaces each int or uint element with its rank
+// among the elements, so a slice with n elements holds a permutation of 0
+// to n-1. The order of the permutation is decided by the filled values.
+// Permutations need a fixed number of elements, i.e. an array or a slice
+// with a fuzz-slice-range.
+//
+// Under BreadthFirst traversal the values pointed to by the elements are
+// filled after the elements are arranged, so they can't be used to sort
+// the elements, see WithTraversal. Unbounded slices, which grow one element
+// at a time, are arranged once filling is finished.
+//
+// Permutations panic if the elements' type can't hold the rank of the last
+// element, e.g. a [300]uint8.
+
+type sortMethodTag struct {
+	wasSet     bool
+	methodName string
+	method     reflect.Value
+}
+
+func newSortMethodTag(structVal reflect.Value, field reflect.StructField, tag string) sortMethodTag {
+	methodName, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return sortMethodTag{}
+	}
+
+	if !isExported(methodName) {
+		panic(fmt.Errorf("%s.%s() is not exported and can't be called", structVal.Type(), methodName))
+	}
+
+	method := structVal.Addr().MethodByName(methodName)
+	if !method.IsValid() {
+		method = structVal.MethodByName(methodName)
+		if !method.IsValid() {
+			panic(fmt.Errorf("%s.%s() could not be found and can't be called", structVal.Type(), methodName))
+		}
+	}
+
+	elemType := elementType(field.Type)
+	methodType := method.Type()
+	if elemType == nil ||
+		methodType.NumIn() != 2 || !elemType.AssignableTo(methodType.In(0)) || !elemType.AssignableTo(methodType.In(1)) ||
+		methodType.NumOut() != 1 || methodType.Out(0).Kind() != reflect.Bool {
+		panic(fmt.Errorf("%s.%s has %s %s.%s(), but it is not a func(a, b %s) bool", structVal.Type(), field.Name, tag, structVal.Type(), methodName, typeName(elemType)))
+	}
+
+	return sortMethodTag{
+		wasSet:     true,
+		methodName: methodName,
+		method:     method,
+	}
+}
+
+// Checks that the tags which arrange elements can be applied to field
+func validateElementTags(structVal reflect.Value, field reflect.StructField, prefix string, t fuzzTags) {
+	if !t.unique.enabled && !t.sorted.enabled && !t.sortMethod.wasSet && !t.permutation.enabled {
+		return
+	}
+
+	elemType := elementType(field.Type)
+	if elemType == nil {
+		panic(fmt.Errorf("%s.%s has %sunique, %ssorted or %spermutation tags, but %s is not a slice or array", structVal.Type(), field.Name, prefix, prefix, prefix, field.Type))
+	}
+
+	if t.unique.enabled && !elemType.Comparable() {
+		panic(fmt.Errorf("%s.%s has %sunique, but its elements of type %s are not comparable", structVal.Type(), field.Name, prefix, elemType))
+	}
+
+	if t.sorted.enabled && !t.sortMethod.wasSet && !isOrdered(elemType) {
+		panic(fmt.Errorf("%s.%s has %ssorted, but its elements of type %s can't be sorted without a %ssort-method", structVal.Type(), field.Name, prefix, elemType, prefix))
+	}
+
+	if t.permutation.enabled {
+		switch elemType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			panic(fmt.Errorf("%s.%s has %spermutation, but its elements of type %s are not ints or uints", structVal.Type(), field.Name, prefix, elemType))
+		}
+		if containerType(field.Type).Kind() == reflect.Slice && !t.sliceRange.uintRange.wasSet {
+			panic(fmt.Errorf("%s.%s has %spermutation, but no %sslice-range", structVal.Type(), field.Name, prefix, prefix))
+		}
+
+		// The largest rank must fit in the elements' type
+		maxLen := t.sliceRange.uintRange.uintMax
+		if containerType(field.Type).Kind() == reflect.Array {
+			maxLen = uint64(containerType(field.Type).Len())
+		}
+		maxRank := uintTypeMax(elemType)
+		if elemType.Kind() >= reflect.Int && elemType.Kind() <= reflect.Int64 {
+			_, typeMax := intTypeBounds(elemType)
+			maxRank = uint64(typeMax)
+		}
+		if maxLen > 0 && maxLen-1 > maxRank {
+			panic(fmt.Errorf("%s.%s has %spermutation, but its elements of type %s can't rank %d elements", structVal.Type(), field.Name, prefix, elemType, maxLen))
+		}
+	}
+}
+
+// Returns typ, looking through pointers
+func containerType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// Returns the type of the elements of the slice or array in typ, looking
+// through pointers. Returns nil if typ isn't a slice or array.
+func elementType(typ reflect.Type) reflect.Type {
+	typ = containerType(typ)
+	if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+		return nil
+	}
+	return typ.Elem()
+}
+
+func typeName(typ reflect.Type) string {
+	if typ == nil {
+		return "T"
+	}
+	return typ.String()
+}
+
+// Returns true if the values of typ have a natural order
+func isOrdered(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns true if any of the tags which arrange elements are set
+func (t fuzzTags) arranged() bool {
+	return t.unique.enabled || t.sorted.enabled || t.sortMethod.wasSet || t.permutation.enabled
+}
+
+// Arranges the elements of the slice or array value, as set by tags
+func arrangeElements(value reflect.Value, tags fuzzTags) {
+	if !tags.arranged() || value.Len() == 0 {
+		return
+	}
+
+	if tags.permutation.enabled {
+		rankElements(value)
+	}
+	if tags.unique.enabled {
+		uniqueElements(value, tags.elementTags())
+	}
+	if tags.sorted.enabled || tags.sortMethod.wasSet {
+		elems := elementValues(value)
+		slices.SortStableFunc(elems, func(a, b reflect.Value) int {
+			return tags.compareElements(a, b)
+		})
+		for i, elem := range elems {
+			value.Index(i).Set(elem)
+		}
+	}
+}
+
+// Returns copies of the elements of value
+func elementValues(value reflect.Value) []reflect.Value {
+	elems := []reflect.Value{}
+	for i := range value.Len() {
+		elem := reflect.New(value.Type().Elem()).Elem()
+		elem.Set(value.Index(i))
+		elems = append(elems, elem)
+	}
+	return elems
+}
+
+// Replaces each int or uint element with its rank among the elements,
+// equal elements are ranked in the order they appear
+func rankElements(value reflect.Value) {
+	elems := elementValues(value)
+	order := make([]int, len(elems))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return compareOrdered(elems[a], elems[b])
+	})
+
+	for rank, i := range order {
+		elem := value.Index(i)
+		if elem.CanInt() {
+			elem.SetInt(int64(rank))
+		} else {
+			elem.SetUint(uint64(rank))
+		}
+	}
+}
+
+// Replaces each element equal to an earlier element, see nextValue
+func uniqueElements(value reflect.Value, elemTags fuzzTags) {
+	seen := map[any]bool{}
+	for i := range value.Len() {
+		elem := value.Index(i)
+		// At most one replacement per earlier element is needed
+		for range i {
+			if !seen[elem.Interface()] || !nextValue(elem, elemTags) {
+				break
+			}
+		}
+		seen[elem.Interface()] = true
+	}
+}
+
+// Returns the index of the first element equal to an earlier element, or -1
+func duplicateElement(value reflect.Value) int {
+	seen := map[any]bool{}
+	for i := range value.Len() {
+		elem := value.Index(i).Interface()
+		if seen[elem] {
+			return i
+		}
+		seen[elem] = true
+	}
+	return -1
+}
+
+// Returns true if the elements of value are ordered by tags
+func (t fuzzTags) elementsSorted(value reflect.Value) bool {
+	for i := 1; i < value.Len(); i++ {
+		if t.compareElements(value.Index(i-1), value.Index(i)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if the elements of value are a permutation of 0 to n-1
+func isPermutation(value reflect.Value) bool {
+	found := make([]bool, value.Len())
+	for i := range value.Len() {
+		elem := value.Index(i)
+		var rank uint64
+		if elem.CanInt() {
+			if elem.Int() < 0 {
+				return false
+			}
+			rank = uint64(elem.Int())
+		} else {
+			rank = elem.Uint()
+		}
+		if rank >= uint64(len(found)) || found[rank] {
+			return false
+		}
+		found[rank] = true
+	}
+	return true
+}
+
+func (t fuzzTags) compareElements(a, b reflect.Value) int {
+	if !t.sortMethod.wasSet {
+		return compareOrdered(a, b)
+	}
+
+	less := func(a, b reflect.Value) bool {
+		return t.sortMethod.method.Call([]reflect.Value{a, b})[0].Bool()
+	}
+	switch {
+	case less(a, b):
+		return -1
+	case less(b, a):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Compares two values with a natural order, see isOrdered
+func compareOrdered(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Bool:
+		return cmp.Compare(boolRank(a.Bool()), boolRank(b.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	default:
+		panic(fmt.Errorf("values of type %s have no natural order", a.Type()))
+	}
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
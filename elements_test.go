@@ -0,0 +1,152 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type elementsStruct struct {
+	UniqueField      []int8    `fuzz-slice-range:"4,4" fuzz-int-range:"0,9" fuzz-unique:"true"`
+	SortedField      []string  `fuzz-slice-range:"3,3" fuzz-string-range:"1,1" fuzz-sorted:"true"`
+	KeysField        [4]uint8  `fuzz-unique:"true" fuzz-sorted:"true"`
+	PermutationField []int     `fuzz-slice-range:"4,4" fuzz-permutation:"true"`
+	PointsField      []point   `fuzz-slice-range:"3,3" fuzz-sort-method:"ByX"`
+	GridField        [][]uint8 `fuzz-slice-range:"2,2;2,2" fuzz-sorted:";true"`
+}
+
+type point struct {
+	X, Y int8
+}
+
+func (s *elementsStruct) ByX(a, b point) bool {
+	return a.X < b.X
+}
+
+func TestFill_ArrangeElements(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// UniqueField, duplicates are replaced with the next value in range
+	c.pushUint64(4, bytesForNative)
+	c.pushInt64(9, bytesFor8)
+	c.pushInt64(9, bytesFor8)
+	c.pushInt64(1, bytesFor8)
+	c.pushInt64(0, bytesFor8)
+	// SortedField
+	c.pushUint64(3, bytesForNative)
+	c.pushString("c")
+	c.pushString("a")
+	c.pushString("b")
+	// KeysField
+	c.pushBytes([]byte{7, 3, 7, 1})
+	// PermutationField, ranked by the filled values
+	c.pushUint64(4, bytesForNative)
+	c.pushInt64(50, bytesForNative)
+	c.pushInt64(-3, bytesForNative)
+	c.pushInt64(100, bytesForNative)
+	c.pushInt64(7, bytesForNative)
+	// PointsField
+	c.pushUint64(3, bytesForNative)
+	c.pushBytes([]byte{3, 0, 1, 1, 2, 2})
+	// GridField
+	c.pushUint64(2, bytesForNative)
+	c.pushUint64(2, bytesForNative)
+	c.pushBytes([]byte{9, 8})
+	c.pushUint64(2, bytesForNative)
+	c.pushBytes([]byte{6, 7})
+
+	val := elementsStruct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.Equal(t, elementsStruct{
+		UniqueField:      []int8{9, 0, 1, 2},
+		SortedField:      []string{"a", "b", "c"},
+		KeysField:        [4]uint8{1, 3, 7, 8},
+		PermutationField: []int{2, 0, 3, 1},
+		PointsField:      []point{{1, 1}, {2, 2}, {3, 0}},
+		GridField:        [][]uint8{{8, 9}, {6, 7}},
+	}, val)
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	filled := elementsStruct{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+}
+
+type unboundedElementsStruct struct {
+	ValuesField []int8 `fuzz-unique:"true" fuzz-sorted:"true"`
+}
+
+// Unbounded slices are arranged once they stop growing
+func TestFill_ArrangeUnboundedElements(t *testing.T) {
+	val := unboundedElementsStruct{}
+	Fill(&val, []byte{5, 3, 5, 1})
+	assert.Equal(t, []int8{1, 3, 5, 6}, val.ValuesField)
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	filled := unboundedElementsStruct{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+}
+
+func TestEncode_ArrangeElements(t *testing.T) {
+	_, err := Encode(&elementsStruct{UniqueField: []int8{1, 2, 1, 3}})
+	assert.EqualError(t, err, "cannot encode *(elementsStruct).UniqueField ([]int8): element 2 is a duplicate of an earlier element")
+
+	_, err = Encode(&elementsStruct{UniqueField: []int8{1, 2, 3, 4}, SortedField: []string{"b", "a", "c"}})
+	assert.EqualError(t, err, "cannot encode *(elementsStruct).SortedField ([]string): its elements are not sorted")
+
+	_, err = Encode(&elementsStruct{UniqueField: []int8{1, 2, 3, 4}, SortedField: []string{"a", "b", "c"}, KeysField: [4]uint8{1, 2, 3, 4}, PermutationField: []int{0, 1, 1, 3}})
+	assert.EqualError(t, err, "cannot encode *(elementsStruct).PermutationField ([]int): it is not a permutation of 0 to 3")
+}
+
+type badSortedTag struct {
+	PointsField []point `fuzz-sorted:"true"`
+}
+
+type badUniqueTag struct {
+	IntField int `fuzz-unique:"true"`
+}
+
+type badPermutationTag struct {
+	PermutationField []int `fuzz-permutation:"true"`
+}
+
+type badPermutationArrayTag struct {
+	PermutationField [200]int8 `fuzz-permutation:"true"`
+}
+
+type badPermutationSliceTag struct {
+	PermutationField []uint8 `fuzz-slice-range:"1,300" fuzz-permutation:"true"`
+}
+
+type badSortMethodTag struct {
+	PointsField []point `fuzz-sort-method:"Less"`
+}
+
+func (s *badSortMethodTag) Less(a, b int) bool {
+	return a < b
+}
+
+func TestFill_BadElementTags(t *testing.T) {
+	assert.PanicsWithError(t, "fuzzhelper.badSortedTag.PointsField has fuzz-sorted, but its elements of type fuzzhelper.point can't be sorted without a fuzz-sort-method", func() {
+		Fill(&badSortedTag{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badUniqueTag.IntField has fuzz-unique, fuzz-sorted or fuzz-permutation tags, but int is not a slice or array", func() {
+		Fill(&badUniqueTag{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badPermutationTag.PermutationField has fuzz-permutation, but no fuzz-slice-range", func() {
+		Fill(&badPermutationTag{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badPermutationArrayTag.PermutationField has fuzz-permutation, but its elements of type int8 can't rank 200 elements", func() {
+		Fill(&badPermutationArrayTag{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badPermutationSliceTag.PermutationField has fuzz-permutation, but its elements of type uint8 can't rank 300 elements", func() {
+		Fill(&badPermutationSliceTag{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badSortMethodTag.PointsField has fuzz-sort-method fuzzhelper.badSortMethodTag.Less(), but it is not a func(a, b fuzzhelper.point) bool", func() {
+		Fill(&badSortMethodTag{}, []byte{1})
+	})
+}
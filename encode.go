@@ -0,0 +1,641 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var _ valueVisitor = &encodeVisitor{}
+
+// The encodeVisitor walks an already filled value, in the same order as
+// Fill, and writes out the bytes which Fill would need to consume to build
+// that value.
+type encodeVisitor struct {
+	config fillConfig
+
+	out *byteConsumer
+	err error
+
+	// Unbounded slices are filled one element at a time, we track how many
+	// elements of each slice have been encoded
+	sliceProgress map[uintptr]int
+	// The entries of each map, in the order they are encoded
+	mapEntries map[uintptr][][2]reflect.Value
+	// Every pointer encoded so far, in the order Fill allocates them
+	pointers pointerHistory
+
+	// Fill only leaves recursive pointers nil when it runs out of bytes,
+	// so the output is truncated at the first one we find
+	truncated    bool
+	truncateAt   int
+	truncateTail int
+	truncation   string
+	// Why Fill runs out of bytes at the truncation
+	truncationReason string
+	lostBits         bool
+}
+
+// Encode returns bytes which, when passed to Fill, will fill a value equal
+// to root. This is the inverse of Fill and can be used to build seed inputs
+// or to migrate inputs between versions of a type, see MigrateCorpus.
+//
+// Not every value can be encoded. Values which violate their fuzz tags, for
+// example a value outside of its range or not returned by its method, cause
+// an error to be returned. Strings must be valid UTF-8 and floats with a
+// range may not round trip exactly. Strings shorter than their minimum
+// length are padded with bytes which Fill drops.
+//
+// Unbounded slices, i.e. slices without a fuzz-slice-range tag, only round
+// trip exactly if they are the last values filled, as they keep growing
+// while there are bytes available. Nil pointers are encoded as pointers to
+// zero values, except for pointers to recursive types. Fill only leaves
+// these nil when it runs out of bytes, so the encoded bytes end there. With
+// MinimumOnExhaustion every nil pointer ends the encoded bytes, and the
+// values after it must be their minimums.
+func Encode(root any, opts ...Option) ([]byte, error) {
+	rootVal := reflect.ValueOf(root)
+	if rootVal.Kind() != reflect.Pointer || rootVal.IsNil() {
+		return nil, fmt.Errorf("can only encode a non-nil pointer, found %s", rootVal.Type())
+	}
+
+	// We encode a copy of root, because nil pointers, maps and
+	// interfaces are allocated while encoding
+	copied := reflect.New(rootVal.Type().Elem())
+	copyPointee(copied, rootVal)
+
+	v := &encodeVisitor{
+		config:        newFillConfig(opts),
+		out:           newByteConsumer([]byte{}),
+		sliceProgress: map[uintptr]int{},
+		mapEntries:    map[uintptr][][2]reflect.Value{},
+		pointers:      pointerHistory{},
+	}
+	v.pointers.record(copied)
+	if v.config.packBits {
+		v.out.enableBitPacking()
+	}
+	if v.config.layout == SplitLayout {
+		v.out.enableSplit()
+	}
+	// Encoding never consumes any bytes, this consumer is never exhausted
+	visitRoot(v, copied.Interface(), newByteConsumer([]byte{1}), v.config.traversal)
+
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	if v.truncated {
+		// Control values pushed after the truncation point are at the
+		// start of the tail
+		head, tail := v.out.bytes, v.out.tail
+		lostHead := head[v.truncateAt:]
+		lostTail := tail[:len(tail)-v.truncateTail]
+		if v.lostBits || slices.ContainsFunc(lostHead, isNonZero) || slices.ContainsFunc(lostTail, isNonZero) {
+			return nil, fmt.Errorf("cannot encode %s: %s, but is followed by values which would be lost", v.truncation, v.truncationReason)
+		}
+		v.out.bytes = head[:v.truncateAt]
+		v.out.tail = tail[len(tail)-v.truncateTail:]
+	}
+	return v.out.getRawBytes(), nil
+}
+
+func isNonZero(b byte) bool {
+	return b != 0
+}
+
+// Marks the point where Fill should run out of bytes
+func (v *encodeVisitor) truncate(value reflect.Value, path valuePath, reason string) {
+	if v.truncated {
+		return
+	}
+
+	v.truncated = true
+	v.truncateAt = len(v.out.bytes)
+	v.truncateTail = len(v.out.tail)
+	v.truncation = path.pathString(value)
+	v.truncationReason = reason
+}
+
+func (v *encodeVisitor) fail(value reflect.Value, path valuePath, format string, args ...any) {
+	if v.err != nil {
+		// Only the first error is reported
+		return
+	}
+	v.err = fmt.Errorf("cannot encode %s: %s", path.pathString(value), fmt.Sprintf(format, args...))
+}
+
+func (v *encodeVisitor) optionIndex(value reflect.Value, path valuePath, tags fuzzTags, index, n int) {
+	if index < 0 {
+		v.fail(value, path, "value %v is not one of the method options", value.Interface())
+		return
+	}
+	v.pushOption(tags, index, n)
+}
+
+// Pushes the choice of option index, of n, weighted if the tags have weights
+func (v *encodeVisitor) pushOption(tags fuzzTags, index, n int) {
+	if tags.weights.wasSet {
+		v.pushChoice(tags.weights.slot(index), tags.weights.total)
+		return
+	}
+	v.pushChoice(index, n)
+}
+
+func (v *encodeVisitor) pushChoice(index, n int) {
+	if v.truncated && v.out.packBits && n <= maxPackedChoices && index != 0 {
+		// Bits may be packed into a reservoir byte before the
+		// truncation point, and would be lost
+		v.lostBits = true
+	}
+	v.out.pushChoice(index, n, v.config.format.choiceSize(n))
+}
+
+func (v *encodeVisitor) length(value reflect.Value, path valuePath, length int, r lengthTagRange) {
+	offset, ok := r.uintRange.offset(uint64(length))
+	if !ok {
+		v.fail(value, path, "length %d is outside of range %s", length, intervalsString(r.uintRange.parts()))
+		return
+	}
+	if v.config.format == CompactFormat {
+		v.out.pushControlUint64(offset, v.config.format.lengthSize(r))
+		return
+	}
+	v.out.pushControlInt64(int64(offset), bytesForNative)
+}
+
+func (v *encodeVisitor) visitBool(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	if !value.CanSet() {
+		return
+	}
+
+	if v.truncated && v.out.packBits && value.Bool() {
+		// See pushChoice
+		v.lostBits = true
+	}
+	v.out.pushBool(value.Bool())
+}
+
+func (v *encodeVisitor) visitInt(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	if !value.CanSet() {
+		return
+	}
+
+	val := value.Int()
+
+	if tags.intValues.wasSet {
+		v.optionIndex(value, path, tags, slices.Index(tags.intValues.value, val), len(tags.intValues.value))
+		return
+	}
+
+	if v.config.biased(tags) {
+		// Encoded values are never taken from the edge values
+		v.out.pushNoSelector()
+	}
+
+	r := tags.intRange.within(value)
+	if !r.valid() {
+		v.out.pushInt64(val, value.Type().Size())
+		return
+	}
+
+	offset, ok := r.offset(val)
+	if !ok {
+		v.fail(value, path, "value %d is outside of range %s", val, intervalsString(r.parts()))
+		return
+	}
+	v.out.pushUint64(offset, v.config.format.numberSize(value, r.span()))
+}
+
+func (v *encodeVisitor) visitUint(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	if !value.CanSet() {
+		return
+	}
+
+	val := value.Uint()
+
+	if tags.uintValues.wasSet {
+		v.optionIndex(value, path, tags, slices.Index(tags.uintValues.value, val), len(tags.uintValues.value))
+		return
+	}
+
+	if v.config.biased(tags) {
+		// Encoded values are never taken from the edge values
+		v.out.pushNoSelector()
+	}
+
+	r := tags.uintRange.within(value)
+	if r.valid() {
+		offset, ok := r.offset(val)
+		if !ok {
+			v.fail(value, path, "value %d is outside of range %s", val, intervalsString(r.parts()))
+			return
+		}
+		val = offset
+	}
+
+	v.out.pushUint64(val, v.config.format.numberSize(value, r.span()))
+}
+
+func (v *encodeVisitor) visitUintptr(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *encodeVisitor) visitFloat(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	if !value.CanSet() {
+		return
+	}
+
+	val := value.Float()
+
+	if tags.floatValues.wasSet {
+		v.optionIndex(value, path, tags, slices.Index(tags.floatValues.value, val), len(tags.floatValues.value))
+		return
+	}
+
+	if tags.floatSpecial.wasSet {
+		if index := floatIndex(tags.floatSpecial.values(value), val); index >= 0 {
+			v.out.pushSelector(index)
+			return
+		}
+		v.out.pushNoSelector()
+	}
+
+	if v.config.biased(tags) {
+		// Encoded values are never taken from the edge values
+		v.out.pushNoSelector()
+	}
+
+	r := tags.floatRange.within(value)
+	if parts := r.parts(); r.valid() && len(parts) > 1 {
+		index := slices.IndexFunc(parts, func(i interval[float64]) bool {
+			return val >= i.lo && val <= i.hi
+		})
+		if index < 0 {
+			v.fail(value, path, "value %g is outside of range %s", val, intervalsString(parts))
+			return
+		}
+		v.pushChoice(index, len(parts))
+		r = r.interval(index)
+	}
+	if r.valid() && r.floatMin < r.floatMax {
+		offset, ok := r.offset(val)
+		if !ok {
+			v.fail(value, path, "value %g is outside of range %s", val, intervalsString(r.parts()))
+			return
+		}
+		if val == r.floatMax {
+			// Fill only produces the max value from positive infinity
+			val = math.Inf(1)
+		} else {
+			val = offset
+		}
+	}
+
+	v.out.pushFloat64(val, value.Type().Size())
+}
+
+func (v *encodeVisitor) visitComplex(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *encodeVisitor) visitArray(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Arrays have a fixed size, only their elements are encoded
+}
+
+// Pushes the control byte for a pointer or interface which may be nil.
+// Returns false if value is nil, and there is nothing more to encode.
+func (v *encodeVisitor) encodeNil(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	percent := v.config.nilChance(tags)
+	if percent == 0 {
+		return true
+	}
+
+	if value.IsNil() {
+		v.out.pushNil(true)
+		return false
+	}
+
+	if percent == 100 {
+		v.fail(value, path, "it is not nil, but is always nil")
+		return false
+	}
+	v.out.pushNil(false)
+	return true
+}
+
+// Returns true if value is at the maximum depth, and must be left nil or
+// empty. Fails if value is not.
+func (v *encodeVisitor) atMaxDepth(value reflect.Value, tags fuzzTags, path valuePath, empty bool) bool {
+	if !v.config.atMaxDepth(tags, path) {
+		return false
+	}
+	if !empty {
+		v.fail(value, path, "it must be nil or empty at the maximum depth")
+	}
+	return true
+}
+
+func (v *encodeVisitor) visitPointer(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
+	if !value.CanSet() || v.atMaxDepth(value, tags, path, value.IsNil()) {
+		return true
+	}
+
+	if !v.encodeNil(value, tags, path) {
+		return true
+	}
+
+	if value.IsNil() {
+		if path.containsType(value.Type().Elem()) || v.config.exhaustion == MinimumOnExhaustion {
+			// This pointer ends a recursive type, or the values
+			// after it are minimums, Fill must run out of bytes here
+			v.truncate(value, path, "it is nil")
+			return true
+		}
+
+		// Fill always allocates pointers, the best we can do is
+		// encode a pointer to a zero value
+		value.Set(reflect.New(value.Type().Elem()))
+	}
+
+	if v.config.aliased(tags) {
+		return v.encodeAlias(value, path)
+	}
+
+	if path.containsPointer(value) {
+		v.fail(value, path, "it points to a value containing it, which requires aliasing")
+		return false
+	}
+
+	if v.pointers.index(value) >= 0 {
+		// Fill can't share this value with the pointer which was
+		// encoded before, so it is encoded again
+		value.Set(unsharedCopy(value))
+	}
+	v.pointers.record(value)
+	return true
+}
+
+// Encodes whether value reuses a pointer Fill has already allocated. Returns
+// true if it doesn't, and the value it points to must be encoded.
+func (v *encodeVisitor) encodeAlias(value reflect.Value, path valuePath) bool {
+	index := v.pointers.index(value)
+	if index < 0 {
+		v.out.pushNoSelector()
+		v.pointers.record(value)
+		return true
+	}
+
+	if index > maxSelector {
+		v.fail(value, path, "it reuses pointer %d of its type, but only the first %d can be reused", index, maxSelector+1)
+		return false
+	}
+	v.out.pushSelector(index)
+	return false
+}
+
+func (v *encodeVisitor) visitSlice(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) (from, to int) {
+	if !value.CanSet() || v.atMaxDepth(value, tags, path, value.Len() == 0) {
+		return 0, 0
+	}
+
+	if tags.sliceRange.uintRange.wasSet {
+		v.length(value, path, value.Len(), tags.sliceRange)
+		return 0, value.Len()
+	}
+
+	// Unbounded slices are filled one element at a time
+	progress := v.sliceProgress[value.UnsafeAddr()]
+	if progress >= value.Len() {
+		return progress, progress
+	}
+	v.sliceProgress[value.UnsafeAddr()] = progress + 1
+	return progress, progress + 1
+}
+
+func (v *encodeVisitor) visitBytes(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
+	if !v.config.copiesBytes(value, tags, path) {
+		return false
+	}
+
+	bytes := make([]byte, value.Len())
+	reflect.Copy(reflect.ValueOf(bytes), value)
+
+	if value.Kind() == reflect.Slice && !tags.rest.enabled {
+		v.length(value, path, len(bytes), bytesLength(tags))
+	}
+	v.out.pushBytes(bytes)
+
+	if tags.rest.enabled {
+		// Fill takes every byte left, the values after this one must
+		// be empty
+		v.truncate(value, path, "it takes the rest of the input")
+	}
+	return true
+}
+
+func (v *encodeVisitor) visitMap(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) int {
+	if !value.CanSet() {
+		return 0
+	}
+
+	if value.IsNil() {
+		value.Set(reflect.MakeMap(value.Type()))
+	}
+
+	// Sort the entries so that encoding is deterministic
+	entries := [][2]reflect.Value{}
+	iter := value.MapRange()
+	for iter.Next() {
+		key, val := newMapEntry(value)
+		key.Set(iter.Key())
+		val.Set(iter.Value())
+		entries = append(entries, [2]reflect.Value{key, val})
+	}
+	slices.SortFunc(entries, func(a, b [2]reflect.Value) int {
+		return strings.Compare(fmt.Sprintf("%v", a[0].Interface()), fmt.Sprintf("%v", b[0].Interface()))
+	})
+	v.mapEntries[value.Pointer()] = entries
+
+	v.length(value, path, len(entries), tags.mapRange)
+	return len(entries)
+}
+
+func (v *encodeVisitor) visitElements(value reflect.Value, tags fuzzTags, path valuePath) {
+	if !value.CanSet() {
+		return
+	}
+
+	if tags.permutation.enabled && !isPermutation(value) {
+		v.fail(value, path, "it is not a permutation of 0 to %d", value.Len()-1)
+		return
+	}
+	if tags.unique.enabled {
+		if i := duplicateElement(value); i >= 0 {
+			v.fail(value, path, "element %d is a duplicate of an earlier element", i)
+			return
+		}
+	}
+	if (tags.sorted.enabled || tags.sortMethod.wasSet) && !tags.elementsSorted(value) {
+		v.fail(value, path, "its elements are not sorted")
+	}
+}
+
+func (v *encodeVisitor) visitMapKey(mapValue, key reflect.Value, tags fuzzTags) {
+	// The keys of a map are always distinct, Fill never replaces them
+}
+
+func (v *encodeVisitor) mapEntry(mapValue reflect.Value, index int) (key, val reflect.Value) {
+	entry := v.mapEntries[mapValue.Pointer()][index]
+	return entry[0], entry[1]
+}
+
+func (v *encodeVisitor) visitChan(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *encodeVisitor) visitFunc(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *encodeVisitor) visitInterface(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
+	if !value.CanSet() || !tags.interfaceValues.wasSet {
+		return false
+	}
+
+	options := tags.interfaceValues.value
+
+	if v.config.atMaxDepth(tags, path) {
+		if !tags.leafValues.wasSet {
+			v.atMaxDepth(value, tags, path, value.IsNil())
+			return false
+		}
+		return v.encodeLeaf(value, tags, path)
+	}
+
+	if !v.encodeNil(value, tags, path) {
+		return false
+	}
+
+	if value.IsNil() {
+		optionType := reflect.TypeOf(options[0]).Elem()
+		if path.containsType(optionType) {
+			// This interface ends a recursive type, Fill must run
+			// out of bytes here
+			v.truncate(value, path, "it is nil")
+			return false
+		}
+
+		// Fill always sets interfaces with options, the best we can do
+		// is encode a zero value of the first option
+		value.Set(reflect.New(optionType))
+	}
+
+	index := slices.IndexFunc(options, func(option any) bool {
+		return reflect.TypeOf(option) == value.Elem().Type()
+	})
+	if index < 0 {
+		v.fail(value, path, "type %s is not one of the interface options", value.Elem().Type())
+		return false
+	}
+	v.pushOption(tags, index, len(options))
+
+	return true
+}
+
+// Encodes an interface at the maximum depth, which must hold one of the leaf
+// options
+func (v *encodeVisitor) encodeLeaf(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	if !v.encodeNil(value, tags, path) {
+		return false
+	}
+
+	leaves := tags.leafValues.value
+	if value.IsNil() {
+		// As with other interfaces, encode a zero value of the first
+		// leaf option
+		value.Set(reflect.New(reflect.TypeOf(leaves[0]).Elem()))
+	}
+
+	index := slices.IndexFunc(leaves, func(option any) bool {
+		return reflect.TypeOf(option) == value.Elem().Type()
+	})
+	if index < 0 {
+		v.fail(value, path, "type %s is not one of the leaf options at the maximum depth", value.Elem().Type())
+		return false
+	}
+	v.pushChoice(index, len(leaves))
+
+	return true
+}
+
+func (v *encodeVisitor) visitString(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	if !value.CanSet() {
+		return
+	}
+
+	val := value.String()
+
+	if tags.stringValues.wasSet {
+		v.optionIndex(value, path, tags, slices.Index(tags.stringValues.value, val), len(tags.stringValues.value))
+		return
+	}
+
+	if tags.regex.wasSet {
+		picks, ok := regexPicks(tags.regex, val)
+		if !ok {
+			v.fail(value, path, "string %q does not match regex %s", val, tags.regex.pattern)
+			return
+		}
+		v.out.pushRegexPicks(picks, v.config.format)
+		return
+	}
+
+	if tags.charset.raw {
+		v.length(value, path, len(val), tags.stringRange)
+		v.out.pushBytes([]byte(val))
+		return
+	}
+
+	if !utf8.ValidString(val) {
+		v.fail(value, path, "string %q is not valid UTF-8", val)
+		return
+	}
+
+	if tags.charset.wasSet {
+		v.length(value, path, utf8.RuneCountInString(val), tags.stringRange)
+		if i := v.out.pushCharsetString(val, tags.charset); i >= 0 {
+			v.fail(value, path, "rune %d of string %q is not in charset %s", i, val, tags.charset.name)
+		}
+		return
+	}
+
+	// Fill drops bytes which aren't valid UTF-8, so a string shorter than
+	// its minimum length is padded with invalid bytes
+	bytes := []byte(val)
+	for uint64(len(bytes)) < tags.stringRange.uintRange.uintMin {
+		bytes = append(bytes, invalidUTF8)
+	}
+	v.length(value, path, len(bytes), tags.stringRange)
+	v.out.pushBytes(bytes)
+}
+
+// A byte which is never part of a valid UTF-8 string
+const invalidUTF8 = 0xff
+
+func (v *encodeVisitor) visitStruct(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	return true
+}
+
+func (v *encodeVisitor) visitUnsafePointer(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *encodeVisitor) visitExhausted(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	// The encodeVisitor never consumes any bytes, so it should never be
+	// exhausted
+	return false
+}
@@ -0,0 +1,180 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode_SimpleTypes(t *testing.T) {
+	// The same type as the simple types test in fill_struct_test.go
+	type testStruct struct {
+		IntValue   int
+		Int64Value int64
+		Int32Value int32
+		Int16Value int16
+		Int8Value  int8
+
+		UintValue   uint
+		Uint64Value uint64
+		Uint32Value uint32
+		Uint16Value uint16
+		Uint8Value  uint8
+
+		Float64Value float64
+		Float32Value float32
+
+		Bool1Value bool
+		Bool2Value bool
+
+		String1Value string
+		String2Value string
+		String3Value string
+		String4Value string
+
+		ArrayValue [4]int
+		SliceValue []uint
+
+		MapValue map[string]float64
+	}
+
+	// Encoding the value filled by the simple types test produces exactly
+	// the bytes used to fill it
+	expected := buildSimpleTestByteConsumer().getRawBytes()
+
+	val := testStruct{}
+	Fill(&val, expected)
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	assert.Equal(t, expected, encoded)
+}
+
+type encodeTagsStruct struct {
+	IntField       int16              `fuzz-int-range:"-10,10"`
+	UintField      uint32             `fuzz-uint-range:"5,15"`
+	FloatField     float64            `fuzz-float-range:"1,2"`
+	StringField    string             `fuzz-string-method:"StringOptions"`
+	SliceField     []int8             `fuzz-slice-range:"1,4"`
+	MapField       map[string]float32 `fuzz-map-range:"0,3" fuzz-string-range:"1,5"`
+	InterfaceField interfaceDemo      `fuzz-interface-method:"InterfaceOptions"`
+	PointerField   *encodeTagsStruct
+}
+
+func (s *encodeTagsStruct) StringOptions() []string {
+	return []string{"a", "b", "c"}
+}
+
+func (s *encodeTagsStruct) InterfaceOptions() []interfaceDemo {
+	return []interfaceDemo{&interfaceDemoA{}, &interfaceDemoB{}}
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	val := encodeTagsStruct{
+		IntField:    -7,
+		UintField:   15,
+		FloatField:  1.5,
+		StringField: "c",
+		SliceField:  []int8{1, -2, 3},
+		MapField: map[string]float32{
+			"one":   1.5,
+			"three": -3,
+		},
+		InterfaceField: &interfaceDemoB{},
+		PointerField: &encodeTagsStruct{
+			IntField:       10,
+			UintField:      5,
+			FloatField:     2,
+			StringField:    "a",
+			SliceField:     []int8{4},
+			MapField:       map[string]float32{},
+			InterfaceField: &interfaceDemoA{},
+		},
+	}
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+
+	// Fill runs out of bytes at the nested pointer field, so it is left
+	// nil
+	filled := encodeTagsStruct{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+}
+
+func TestEncode_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		value         encodeTagsStruct
+		expectedError string
+	}{
+		{
+			name:          "int out of range",
+			value:         encodeTagsStruct{IntField: 11},
+			expectedError: "cannot encode *(encodeTagsStruct).IntField (int16): value 11 is outside of range -10 to 10",
+		},
+		{
+			name:          "uint out of range",
+			value:         encodeTagsStruct{UintField: 4},
+			expectedError: "cannot encode *(encodeTagsStruct).UintField (uint32): value 4 is outside of range 5 to 15",
+		},
+		{
+			name:          "not an option",
+			value:         encodeTagsStruct{UintField: 5, FloatField: 1, StringField: "d"},
+			expectedError: "cannot encode *(encodeTagsStruct).StringField (string): value d is not one of the method options",
+		},
+		{
+			name:          "slice too short",
+			value:         encodeTagsStruct{UintField: 5, FloatField: 1, StringField: "a"},
+			expectedError: "cannot encode *(encodeTagsStruct).SliceField ([]int8): length 0 is outside of range 1 to 4",
+		},
+		{
+			name:          "wrong interface option",
+			value:         encodeTagsStruct{UintField: 5, FloatField: 1, StringField: "a", SliceField: []int8{1}, InterfaceField: &interfaceDemoC{}},
+			expectedError: "cannot encode *(encodeTagsStruct).InterfaceField (interface): type *fuzzhelper.interfaceDemoC is not one of the interface options",
+		},
+		{
+			// The fields of InterfaceField are filled after PointerField
+			name:          "values after nil recursive pointer",
+			value:         encodeTagsStruct{UintField: 5, FloatField: 1, StringField: "a", SliceField: []int8{1}, InterfaceField: &interfaceDemoA{IntField: 1}},
+			expectedError: "cannot encode *(encodeTagsStruct).PointerField (*encodeTagsStruct): it is nil, but is followed by values which would be lost",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Encode(&testCase.value)
+			assert.EqualError(t, err, testCase.expectedError)
+		})
+	}
+}
+
+type encodeUnionStruct struct {
+	IntField   int16   `fuzz-int-range:"-10,-5|100,0x7FFF"`
+	UintField  uint32  `fuzz-uint-range:"0,0|0xFFFF,0xFFFF"`
+	FloatField float64 `fuzz-float-range:"0,1|10,11"`
+	SliceField []uint8 `fuzz-slice-range:"1,1|3,3"`
+}
+
+func TestEncode_MultiIntervalRanges(t *testing.T) {
+	val := encodeUnionStruct{
+		IntField:   32767,
+		UintField:  0xFFFF,
+		FloatField: 10.5,
+		SliceField: []uint8{1, 2, 3},
+	}
+
+	for _, format := range []ByteFormat{NativeFormat, CompactFormat} {
+		encoded, err := Encode(&val, WithFormat(format))
+		require.NoError(t, err)
+
+		filled := encodeUnionStruct{}
+		Fill(&filled, encoded, WithFormat(format))
+		assert.Equal(t, val, filled)
+	}
+
+	// A value in the gap between intervals can't be encoded
+	val.IntField = 50
+	_, err := Encode(&val)
+	assert.EqualError(t, err, "cannot encode *(encodeUnionStruct).IntField (int16): value 50 is outside of range -10 to -5, 100 to 32767")
+}
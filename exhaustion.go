@@ -0,0 +1,50 @@
+package fuzzhelper
+
+// ExhaustionPolicy controls what Fill does with the parts of a value which
+// are still unvisited when the input bytes run out.
+type ExhaustionPolicy int
+
+const (
+	// StopOnExhaustion stops filling as soon as the bytes run out. Every
+	// value which has not been visited is left untouched, usually as its
+	// zero value. This means that fields may hold values which violate
+	// their fuzz tags. This is the default policy.
+	StopOnExhaustion ExhaustionPolicy = iota
+
+	// MinimumOnExhaustion continues filling after the bytes run out,
+	// setting every remaining value to the minimum value allowed by its
+	// tags. Ranged values take their range minimum, method values take
+	// their first option and slices, maps and strings take their minimum
+	// length. Pointers and unbounded slices are left nil/empty so the
+	// filled value stays finite. For the same reason slices, maps and
+	// interfaces which would make another value of a recursive type are
+	// left empty, even if this is shorter than their minimum length.
+	MinimumOnExhaustion
+
+	// PRNGOnExhaustion continues filling after the bytes run out, drawing
+	// further bytes from a pseudo-random generator seeded from the input
+	// bytes. This allows short inputs to produce fully populated, deeply
+	// nested values, while remaining deterministic for any given input.
+	//
+	// The number of generated bytes is limited (see WithExpansionLimit),
+	// once the limit is reached the remaining values are filled as for
+	// MinimumOnExhaustion.
+	PRNGOnExhaustion
+)
+
+const defaultExpansionLimit = 4096
+
+// WithExhaustion sets the policy applied when Fill runs out of bytes.
+func WithExhaustion(policy ExhaustionPolicy) Option {
+	return func(config *fillConfig) {
+		config.exhaustion = policy
+	}
+}
+
+// WithExpansionLimit sets the maximum number of pseudo-random bytes which
+// will be generated under the PRNGOnExhaustion policy. The default is 4096.
+func WithExpansionLimit(limit int) Option {
+	return func(config *fillConfig) {
+		config.expansionLimit = limit
+	}
+}
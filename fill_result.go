@@ -0,0 +1,76 @@
+package fuzzhelper
+
+import "reflect"
+
+// FillResult reports how Fill used its input bytes and what it built from
+// them.
+//
+// Paths used as keys, and in TruncatedAt, are formatted the same way as in
+// the output of Describe.
+type FillResult struct {
+	// The number of input bytes consumed
+	BytesConsumed int
+	// The number of input bytes which were not consumed
+	BytesLeft int
+	// The number of bytes generated after the input bytes ran out, see
+	// PRNGOnExhaustion
+	BytesGenerated int
+
+	// The number of values set, per kind of value
+	ValuesSet map[reflect.Kind]int
+	// The length of each slice built, by path
+	SliceLengths map[string]int
+	// The length of each map built, by path
+	MapLengths map[string]int
+
+	// Indicates that the bytes ran out before every value was visited
+	Truncated bool
+	// The path of the first value visited after the bytes ran out
+	TruncatedAt string
+}
+
+// WithResult records statistics about a call to Fill into result.
+func WithResult(result *FillResult) Option {
+	return func(config *fillConfig) {
+		config.result = result
+	}
+}
+
+func newFillResult() FillResult {
+	return FillResult{
+		ValuesSet:    map[reflect.Kind]int{},
+		SliceLengths: map[string]int{},
+		MapLengths:   map[string]int{},
+	}
+}
+
+func (r *FillResult) recordValue(value reflect.Value) {
+	r.ValuesSet[value.Kind()]++
+}
+
+// Slices and maps keep growing after they are first visited, so we record
+// their final lengths after the fill is complete.
+func (r *FillResult) recordLengths(slices, maps map[string]reflect.Value) {
+	for path, value := range slices {
+		r.SliceLengths[path] = value.Len()
+	}
+	for path, value := range maps {
+		r.MapLengths[path] = value.Len()
+	}
+}
+
+func (r *FillResult) recordTruncation(value reflect.Value, path valuePath) {
+	if r.Truncated {
+		// Only the first truncation point is recorded
+		return
+	}
+
+	r.Truncated = true
+	r.TruncatedAt = path.pathString(value)
+}
+
+func (r *FillResult) recordBytes(c *byteConsumer) {
+	r.BytesConsumed = c.inputConsumed
+	r.BytesLeft = c.inputLeft
+	r.BytesGenerated = c.generated
+}
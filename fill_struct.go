@@ -9,10 +9,94 @@ import (
 var _ valueVisitor = &fillVisitor{}
 
 type fillVisitor struct {
+	config fillConfig
+
+	result FillResult
+	slices map[string]reflect.Value
+	maps   map[string]reflect.Value
+	// Every pointer allocated so far, which aliasing pointers may reuse
+	pointers pointerHistory
+	// Unbounded slices whose elements are arranged once filling is
+	// finished, see visitElements
+	unbounded     []arrangement
+	unboundedSeen map[uintptr]bool
 }
 
-func Fill(root any, bytes []byte) {
-	visitRoot(&fillVisitor{}, root, newByteConsumer(bytes))
+// A slice and the tags which arrange its elements
+type arrangement struct {
+	value reflect.Value
+	tags  fuzzTags
+}
+
+func newFillVisitor(config fillConfig) *fillVisitor {
+	return &fillVisitor{
+		config:        config,
+		result:        newFillResult(),
+		pointers:      pointerHistory{},
+		slices:        map[string]reflect.Value{},
+		maps:          map[string]reflect.Value{},
+		unboundedSeen: map[uintptr]bool{},
+	}
+}
+
+func Fill(root any, bytes []byte, opts ...Option) {
+	fill(root, newByteConsumer(bytes), newFillConfig(opts))
+}
+
+// FillPrefix fills root in the same way as Fill, and returns the bytes which
+// were not consumed. This allows a single input to drive a sequence of
+// values, each filled from the bytes left over by the previous one.
+//
+// Note that unbounded slices, i.e. slices without a fuzz-slice-range tag,
+// keep growing until all of the bytes are consumed.
+func FillPrefix(root any, bytes []byte, opts ...Option) (rest []byte) {
+	c := newByteConsumer(bytes)
+	fill(root, c, newFillConfig(opts))
+	return c.remaining()
+}
+
+// FillAll fills each of roots in turn, each one being filled from the bytes
+// left over by the previous one. Every root is filled with the same opts.
+// The bytes left over after the last root is filled are returned.
+//
+// The roots are filled from a single Consumer, as by Consumer.Fill, so the
+// bits of a packed byte, the control values of SplitLayout and the bytes
+// generated by PRNGOnExhaustion carry on from one root to the next.
+func FillAll(bytes []byte, roots []any, opts ...Option) (rest []byte) {
+	c := NewConsumer(bytes, opts...)
+	for _, root := range roots {
+		c.Fill(root)
+	}
+	return c.Remaining()
+}
+
+func fill(root any, c *byteConsumer, config fillConfig) {
+	if config.exhaustion == PRNGOnExhaustion && c.expansion == nil {
+		c.enableExpansion(config.expansionLimit)
+	}
+	if config.packBits {
+		c.enableBitPacking()
+	}
+	if config.layout == SplitLayout {
+		c.enableSplit()
+	}
+
+	v := newFillVisitor(config)
+	if rootVal := reflect.ValueOf(root); rootVal.Kind() == reflect.Pointer && !rootVal.IsNil() {
+		v.pointers.record(rootVal)
+	}
+	visitRoot(v, root, c, config.traversal)
+	// Slices found later are nested in those found earlier, so they are
+	// arranged first
+	for i := len(v.unbounded) - 1; i >= 0; i-- {
+		arrangeElements(v.unbounded[i].value, v.unbounded[i].tags)
+	}
+
+	if config.result != nil {
+		v.result.recordBytes(c)
+		v.result.recordLengths(v.slices, v.maps)
+		*config.result = v.result
+	}
 }
 
 func (v *fillVisitor) visitBool(value reflect.Value, c *byteConsumer, _ fuzzTags, path valuePath) {
@@ -24,6 +108,7 @@ func (v *fillVisitor) visitBool(value reflect.Value, c *byteConsumer, _ fuzzTags
 
 	val := c.consumeBool()
 	value.SetBool(val)
+	v.result.recordValue(value)
 }
 
 func (v *fillVisitor) visitInt(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
@@ -35,16 +120,31 @@ func (v *fillVisitor) visitInt(value reflect.Value, c *byteConsumer, tags fuzzTa
 
 	// First check there is a list of valid int values
 	if tags.intValues.wasSet {
-		val := c.consumeUint64(bytesForNative)
-		intVal := tags.intValues.value[val%uint64(len(tags.intValues.value))]
+		intVal := tags.intValues.value[v.consumeChoice(c, tags, len(tags.intValues.value))]
 
 		value.SetInt(intVal)
+		v.result.recordValue(value)
 		return
 	}
 
-	val := c.consumeInt64(value.Type().Size())
-	fittedVal := tags.intRange.fit(val)
+	if v.config.biased(tags) {
+		if selector, ok := c.consumeSelector(); ok {
+			edges := intEdges(value, tags.intRange)
+			value.SetInt(edges[selector%len(edges)])
+			v.result.recordValue(value)
+			return
+		}
+	}
+
+	var fittedVal int64
+	if r := tags.intRange.within(value); r.valid() {
+		val := c.consumeOffset(v.config.format.numberSize(value, r.span()), r.span())
+		fittedVal = r.fitOffset(val)
+	} else {
+		fittedVal = c.consumeInt64(value.Type().Size())
+	}
 	value.SetInt(fittedVal)
+	v.result.recordValue(value)
 }
 
 func (v *fillVisitor) visitUint(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
@@ -56,16 +156,31 @@ func (v *fillVisitor) visitUint(value reflect.Value, c *byteConsumer, tags fuzzT
 
 	// First check there is a list of valid uint values
 	if tags.uintValues.wasSet {
-		val := c.consumeUint64(bytesForNative)
-		uintVal := tags.uintValues.value[val%uint64(len(tags.uintValues.value))]
+		uintVal := tags.uintValues.value[v.consumeChoice(c, tags, len(tags.uintValues.value))]
 
 		value.SetUint(uintVal)
+		v.result.recordValue(value)
 		return
 	}
 
-	val := c.consumeUint64(value.Type().Size())
-	fittedVal := tags.uintRange.fit(val)
+	if v.config.biased(tags) {
+		if selector, ok := c.consumeSelector(); ok {
+			edges := uintEdges(value, tags.uintRange)
+			value.SetUint(edges[selector%len(edges)])
+			v.result.recordValue(value)
+			return
+		}
+	}
+
+	var fittedVal uint64
+	if r := tags.uintRange.within(value); r.valid() {
+		val := c.consumeOffset(v.config.format.numberSize(value, r.span()), r.span())
+		fittedVal = r.fitOffset(val)
+	} else {
+		fittedVal = c.consumeUint64(value.Type().Size())
+	}
 	value.SetUint(fittedVal)
+	v.result.recordValue(value)
 }
 
 func (v *fillVisitor) visitUintptr(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
@@ -82,15 +197,45 @@ func (v *fillVisitor) visitFloat(value reflect.Value, c *byteConsumer, tags fuzz
 
 	// First check there is a list of valid uint values
 	if tags.floatValues.wasSet {
-		val := c.consumeUint64(bytesForNative)
-		floatVal := tags.floatValues.value[val%uint64(len(tags.floatValues.value))]
+		floatVal := tags.floatValues.value[v.consumeChoice(c, tags, len(tags.floatValues.value))]
 		value.SetFloat(floatVal)
+		v.result.recordValue(value)
 		return
 	}
 
-	val := c.consumeFloat64(value.Type().Size())
-	fittedVal := tags.floatRange.fit(val)
+	if tags.floatSpecial.wasSet {
+		if selector, ok := c.consumeSelector(); ok {
+			specials := tags.floatSpecial.values(value)
+			value.SetFloat(specials[selector%len(specials)])
+			v.result.recordValue(value)
+			return
+		}
+	}
+
+	r := tags.floatRange.within(value)
+	var fittedVal float64
+	isEdge := false
+	if v.config.biased(tags) {
+		var selector int
+		if selector, isEdge = c.consumeSelector(); isEdge {
+			edges := floatEdges(value, r)
+			fittedVal = edges[selector%len(edges)]
+		}
+	}
+	if !isEdge {
+		if n := len(r.parts()); n > 1 {
+			r = r.interval(c.consumeChoice(n, v.config.format.choiceSize(n)))
+		}
+		val := c.consumeFloat64(value.Type().Size())
+		fittedVal = r.fit(val)
+	}
+
+	fittedVal = tags.floatDecimals.round(fittedVal, r)
+	if value.Type().Size() == bytesFor32 {
+		fittedVal = fitFloat32(fittedVal, r)
+	}
 	value.SetFloat(fittedVal)
+	v.result.recordValue(value)
 }
 
 func (v *fillVisitor) visitComplex(value reflect.Value, tags fuzzTags, path valuePath) {
@@ -104,11 +249,35 @@ func (v *fillVisitor) visitArray(value reflect.Value, tags fuzzTags, path valueP
 	// Each of it's elements will be visited and we will fill those
 }
 
-func (v *fillVisitor) visitPointer(value reflect.Value, c *byteConsumer, _ fuzzTags, path valuePath) {
+func (v *fillVisitor) visitPointer(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
 	//print(leftPad(len(path.names)))
 	//print("pointer")
 	if !value.CanSet() {
-		return
+		return true
+	}
+
+	if c.len() == 0 {
+		// We have run out of bytes, the minimum value for a pointer is nil
+		return true
+	}
+
+	if v.config.atMaxDepth(tags, path) {
+		return true
+	}
+
+	if percent := v.config.nilChance(tags); percent > 0 && c.consumeNil(percent) {
+		return true
+	}
+
+	if v.config.aliased(tags) {
+		selector, ok := c.consumeSelector()
+		if candidates := v.pointers[value.Type()]; ok && len(candidates) > 0 {
+			// Reuse a pointer, the value it points to has already
+			// been visited
+			value.Set(candidates[selector%len(candidates)])
+			v.result.recordValue(value)
+			return false
+		}
 	}
 
 	// If the value is nil - allocate a value for it to point to
@@ -116,6 +285,9 @@ func (v *fillVisitor) visitPointer(value reflect.Value, c *byteConsumer, _ fuzzT
 	vType := pType.Elem()
 	newVal := reflect.New(vType)
 	value.Set(newVal)
+	v.pointers.record(newVal)
+	v.result.recordValue(value)
+	return true
 }
 
 func (v *fillVisitor) visitSlice(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) (from, to int) {
@@ -126,37 +298,100 @@ func (v *fillVisitor) visitSlice(value reflect.Value, c *byteConsumer, tags fuzz
 
 	initialLen := value.Len()
 
+	if v.config.atMaxDepth(tags, path) {
+		return initialLen, initialLen
+	}
+
 	appendSize := 1
+	if c.len() == 0 && !tags.sliceRange.uintRange.wasSet {
+		// We have run out of bytes, an unbounded slice stops growing
+		appendSize = 0
+	}
 	if tags.sliceRange.uintRange.wasSet {
 		// If we have a size range, use that to determine appendSize
-		val := int(c.consumeInt64(bytesForNative))
-		appendSize = tags.sliceRange.fit(val)
+		appendSize = v.consumeLength(c, tags.sliceRange)
 	}
 
 	toAppend := reflect.MakeSlice(value.Type(), appendSize, appendSize)
 	value.Set(reflect.AppendSlice(value, toAppend))
+	if initialLen == 0 {
+		v.result.recordValue(value)
+		v.trackLength(v.slices, value, path)
+	}
 
 	//print("slice ", sliceLen)
 	return initialLen, value.Len()
 }
 
+func (v *fillVisitor) visitBytes(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
+	if !v.config.copiesBytes(value, tags, path) {
+		return false
+	}
+
+	switch {
+	case value.Kind() == reflect.Array:
+		setBytes(value, c.consume(value.Len()))
+	case tags.rest.enabled:
+		// Generated bytes are not part of the input, so they are not
+		// taken
+		setBytes(value, c.consume(len(c.bytes)))
+	default:
+		setBytes(value, c.consume(v.consumeLength(c, bytesLength(tags))))
+	}
+
+	v.result.recordValue(value)
+	if value.Kind() == reflect.Slice {
+		v.trackLength(v.slices, value, path)
+	}
+	return true
+}
+
 func (v *fillVisitor) visitMap(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) int {
 	//print(leftPad(len(path.names)))
 	if !value.CanSet() {
 		return 0
 	}
 
-	val := int(c.consumeInt64(bytesForNative))
-	mapLen := tags.mapRange.fit(val)
+	mapLen := v.consumeLength(c, tags.mapRange)
 
 	//print("map ", mapLen)
 	mapType := value.Type()
 	newMap := reflect.MakeMapWithSize(mapType, mapLen)
 	value.Set(newMap)
+	v.result.recordValue(value)
+	v.trackLength(v.maps, value, path)
 
 	return mapLen
 }
 
+func (v *fillVisitor) visitElements(value reflect.Value, tags fuzzTags, path valuePath) {
+	if !value.CanSet() {
+		return
+	}
+
+	if value.Kind() == reflect.Slice && !tags.sliceRange.uintRange.wasSet && tags.arranged() {
+		// Unbounded slices grow one element at a time. Rather than
+		// arranging the elements after every element they are
+		// arranged once, after filling is finished.
+		if key := value.Addr().Pointer(); !v.unboundedSeen[key] {
+			v.unboundedSeen[key] = true
+			v.unbounded = append(v.unbounded, arrangement{value: value, tags: tags})
+		}
+		return
+	}
+	arrangeElements(value, tags)
+}
+
+func (v *fillVisitor) visitMapKey(mapValue, key reflect.Value, tags fuzzTags) {
+	if v.config.distinctKeys(tags) {
+		distinctMapKey(mapValue, key, tags.mapKeyTags())
+	}
+}
+
+func (v *fillVisitor) mapEntry(mapValue reflect.Value, index int) (key, val reflect.Value) {
+	return newMapEntry(mapValue)
+}
+
 func (v *fillVisitor) visitChan(value reflect.Value, tags fuzzTags, path valuePath) {
 	// Do nothing - channels are simply not supported
 	// we still visit them so we can _describe_ that we don't support them
@@ -173,10 +408,25 @@ func (v *fillVisitor) visitInterface(value reflect.Value, c *byteConsumer, tags
 	}
 
 	if tags.interfaceValues.wasSet {
-		// Choose one of the values from the interfaceValues slice
-		val := c.consumeUint64(bytesForNative)
-		options := tags.interfaceValues.value
-		chosen := options[val%uint64(len(options))]
+		atMaxDepth := v.config.atMaxDepth(tags, path)
+		if atMaxDepth && !tags.leafValues.wasSet {
+			return false
+		}
+
+		if percent := v.config.nilChance(tags); percent > 0 && c.consumeNil(percent) {
+			return false
+		}
+
+		// Choose one of the values from the interfaceValues slice, or
+		// the leafValues slice at the maximum depth
+		var chosen any
+		if atMaxDepth {
+			leaves := tags.leafValues.value
+			chosen = leaves[c.consumeChoice(len(leaves), v.config.format.choiceSize(len(leaves)))]
+		} else {
+			options := tags.interfaceValues.value
+			chosen = options[v.consumeChoice(c, tags, len(options))]
+		}
 
 		// Use the chosen value (an actual object we use only as an
 		// example) and extract its type so we can build a new instance
@@ -198,6 +448,7 @@ func (v *fillVisitor) visitInterface(value reflect.Value, c *byteConsumer, tags
 		}
 
 		value.Set(newValue)
+		v.result.recordValue(value)
 		return true
 	}
 
@@ -211,18 +462,32 @@ func (v *fillVisitor) visitString(value reflect.Value, c *byteConsumer, tags fuz
 
 	// First check if there is a list of valid string values
 	if tags.stringValues.wasSet {
-		val := c.consumeUint64(bytesForNative)
-		str := tags.stringValues.value[val%uint64(len(tags.stringValues.value))]
+		str := tags.stringValues.value[v.consumeChoice(c, tags, len(tags.stringValues.value))]
 
 		value.SetString(str)
+		v.result.recordValue(value)
 		return
 	}
 
-	lengthVal := int(c.consumeInt64(bytesForNative))
-	strLength := tags.stringRange.fit(lengthVal)
+	if tags.regex.wasSet {
+		value.SetString(c.regexString(tags.regex.re, v.config.format))
+		v.result.recordValue(value)
+		return
+	}
 
-	val := c.String(strLength)
+	strLength := v.consumeLength(c, tags.stringRange)
+
+	var val string
+	switch {
+	case tags.charset.raw:
+		val = c.rawString(strLength)
+	case tags.charset.wasSet:
+		val = c.charsetString(strLength, tags.charset)
+	default:
+		val = c.String(strLength)
+	}
 	value.SetString(val)
+	v.result.recordValue(value)
 }
 
 func (v *fillVisitor) visitStruct(value reflect.Value, tags fuzzTags, path valuePath) bool {
@@ -236,6 +501,84 @@ func (v *fillVisitor) visitUnsafePointer(value reflect.Value, tags fuzzTags, pat
 	// we still visit them so we can _describe_ that we don't support them
 }
 
+// Consumes bytes to choose one of n options, returns the index of the chosen
+// option. If the options are weighted the choice is made between the total
+// of the weights.
+func (v *fillVisitor) consumeChoice(c *byteConsumer, tags fuzzTags, n int) int {
+	if tags.weights.wasSet {
+		total := tags.weights.total
+		return tags.weights.option(c.consumeChoice(total, v.config.format.choiceSize(total)))
+	}
+	return c.consumeChoice(n, v.config.format.choiceSize(n))
+}
+
+func (v *fillVisitor) consumeLength(c *byteConsumer, r lengthTagRange) int {
+	if v.config.format == CompactFormat {
+		val := c.consumeControlUint64(v.config.format.lengthSize(r))
+		return int(r.uintRange.fitOffset(val))
+	}
+
+	val := int(c.consumeControlInt64(bytesForNative))
+	return r.fit(val)
+}
+
+func (v *fillVisitor) trackLength(values map[string]reflect.Value, value reflect.Value, path valuePath) {
+	if v.config.result == nil {
+		// Building the path string is expensive, only do it if the
+		// result was asked for
+		return
+	}
+	values[path.pathString(value)] = value
+}
+
+func (v *fillVisitor) visitExhausted(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	v.result.recordTruncation(value, path)
+
+	if v.config.exhaustion == StopOnExhaustion {
+		return false
+	}
+
+	// Recursive types are cut off at the slices, maps and interfaces
+	// which would make another value of a type being filled, otherwise
+	// values like slices with a minimum length could be filled forever.
+	// These are left empty, rather than making values whose fields are
+	// never filled. Pointers don't need to be cut off, as they are left
+	// nil.
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		if path.containsType(elementStruct(value.Type())) {
+			return false
+		}
+	case reflect.Interface:
+		if tags.interfaceValues.wasSet && !v.config.atMaxDepth(tags, path) {
+			for _, option := range tags.interfaceValues.value {
+				if path.containsType(elementStruct(reflect.TypeOf(option))) {
+					return false
+				}
+			}
+		}
+	}
+
+	// All of the visit methods will receive zeroed bytes from here on,
+	// which fit to the minimum value allowed by the tags
+	return true
+}
+
+// Returns the struct type of the elements in typ, looking through pointers,
+// slices, arrays and maps. Returns nil if the elements aren't structs.
+func elementStruct(typ reflect.Type) reflect.Type {
+	for {
+		switch typ.Kind() {
+		case reflect.Struct:
+			return typ
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			typ = typ.Elem()
+		default:
+			return nil
+		}
+	}
+}
+
 //lint:ignore U1000 This method is used if we uncomment the debug printlns
 func leftPad(l int) string {
 	return strings.Repeat(" ", l)
@@ -1,6 +1,7 @@
 package fuzzhelper
 
 import (
+	"reflect"
 	"testing"
 	"unsafe"
 
@@ -489,3 +490,364 @@ func TestFill_RootSlice(t *testing.T) {
 		}, val)
 	}
 }
+
+type exhaustionStruct struct {
+	IntField       int     `fuzz-int-range:"5,10"`
+	UintField      uint    `fuzz-uint-range:"3,7"`
+	FloatField     float64 `fuzz-float-range:"-1.5,2.5"`
+	StringField    string  `fuzz-string-method:"StringOptions"`
+	StringLenField string  `fuzz-string-range:"2,4"`
+	SliceField     []int   `fuzz-slice-range:"1,5" fuzz-int-range:"-3,3"`
+	MapField       map[int]int
+	UnboundedSlice []int
+	PointerField   *exhaustionStruct
+	InterfaceField interfaceDemo `fuzz-interface-method:"InterfaceOptions"`
+}
+
+func (s *exhaustionStruct) StringOptions() []string {
+	return []string{"first", "second"}
+}
+
+func (s *exhaustionStruct) InterfaceOptions() []interfaceDemo {
+	return []interfaceDemo{&interfaceDemoA{}, &interfaceDemoB{}}
+}
+
+func TestFill_StopOnExhaustion(t *testing.T) {
+	// By default values which aren't reached before the bytes run out are
+	// left as their zero values
+	val := exhaustionStruct{}
+	Fill(&val, []byte{})
+	assert.Equal(t, exhaustionStruct{}, val)
+
+	val = exhaustionStruct{}
+	Fill(&val, []byte{}, WithExhaustion(StopOnExhaustion))
+	assert.Equal(t, exhaustionStruct{}, val)
+}
+
+func TestFill_MinimumOnExhaustion(t *testing.T) {
+	expected := exhaustionStruct{
+		IntField:       5,
+		UintField:      3,
+		FloatField:     -1.5,
+		StringField:    "first",
+		StringLenField: "\x00\x00",
+		SliceField:     []int{-3},
+		MapField:       map[int]int{},
+		UnboundedSlice: nil,
+		PointerField:   nil,
+		InterfaceField: &interfaceDemoA{},
+	}
+
+	val := exhaustionStruct{}
+	Fill(&val, []byte{}, WithExhaustion(MinimumOnExhaustion))
+	assert.Equal(t, expected, val)
+}
+
+func TestFill_MinimumOnExhaustion_PartialBytes(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// IntField
+	c.pushInt64(2, bytesForNative)
+
+	expected := exhaustionStruct{
+		IntField:       7,
+		UintField:      3,
+		FloatField:     -1.5,
+		StringField:    "first",
+		StringLenField: "\x00\x00",
+		SliceField:     []int{-3},
+		MapField:       map[int]int{},
+		UnboundedSlice: nil,
+		PointerField:   nil,
+		InterfaceField: &interfaceDemoA{},
+	}
+
+	val := exhaustionStruct{}
+	Fill(&val, c.getRawBytes(), WithExhaustion(MinimumOnExhaustion))
+	assert.Equal(t, expected, val)
+}
+
+func TestFill_MinimumOnExhaustion_Recursive(t *testing.T) {
+	type node struct {
+		Value    int    `fuzz-int-range:"1,10"`
+		Children []node `fuzz-slice-range:"1,2"`
+	}
+
+	// The recursion is cut off at the Children, no elements are made
+	// for them once the bytes run out
+	val := node{}
+	Fill(&val, []byte{}, WithExhaustion(MinimumOnExhaustion))
+	assert.Equal(t, node{Value: 1}, val)
+
+	c := newByteConsumer([]byte{})
+	c.pushInt64(4, bytesForNative)
+	c.pushUint64(1, bytesForNative)
+	c.pushInt64(6, bytesForNative)
+
+	// The second child is made after the bytes run out, it takes the
+	// minimum of its Value
+	expected := node{
+		Value: 5,
+		Children: []node{
+			{Value: 7},
+			{Value: 1},
+		},
+	}
+
+	val = node{}
+	Fill(&val, c.getRawBytes(), WithExhaustion(MinimumOnExhaustion))
+	assert.Equal(t, expected, val)
+}
+
+type recursiveInterfaceStruct struct {
+	Value int `fuzz-int-range:"1,10"`
+	Next  any `fuzz-interface-method:"NextOptions"`
+}
+
+func (s *recursiveInterfaceStruct) NextOptions() []any {
+	return []any{&recursiveInterfaceStruct{}}
+}
+
+func TestFill_MinimumOnExhaustion_RecursiveInterface(t *testing.T) {
+	val := recursiveInterfaceStruct{}
+	Fill(&val, []byte{}, WithExhaustion(MinimumOnExhaustion))
+	// The recursion is cut off at Next, which is left nil
+	assert.Equal(t, recursiveInterfaceStruct{Value: 1}, val)
+}
+
+func TestFill_PRNGOnExhaustion(t *testing.T) {
+	type innerStruct struct {
+		IntField    int    `fuzz-int-range:"1,100"`
+		StringField string `fuzz-string-range:"1,10"`
+	}
+	type testStruct struct {
+		Inner      *innerStruct
+		SliceField []innerStruct `fuzz-slice-range:"3,3"`
+	}
+
+	bytes := []byte{1, 2, 3}
+
+	val := testStruct{}
+	Fill(&val, bytes, WithExhaustion(PRNGOnExhaustion))
+
+	// The input bytes alone are not enough to reach Inner, but the
+	// generated bytes fill out the whole structure
+	assert.NotNil(t, val.Inner)
+	assert.Len(t, val.SliceField, 3)
+	for _, inner := range append(val.SliceField, *val.Inner) {
+		assert.GreaterOrEqual(t, inner.IntField, 1)
+		assert.LessOrEqual(t, inner.IntField, 100)
+	}
+
+	// The same input always generates the same value
+	other := testStruct{}
+	Fill(&other, bytes, WithExhaustion(PRNGOnExhaustion))
+	assert.Equal(t, val, other)
+
+	// Different inputs generate different values
+	different := testStruct{}
+	Fill(&different, []byte{1, 2, 4}, WithExhaustion(PRNGOnExhaustion))
+	assert.NotEqual(t, val, different)
+}
+
+func TestFill_PRNGOnExhaustion_InputUnchanged(t *testing.T) {
+	type testStruct struct {
+		SliceField []int64 `fuzz-slice-range:"4,4"`
+	}
+
+	data := []byte{1, 2, 3, 4, 9, 9, 9, 9, 9, 9, 9, 9}
+
+	// Generated bytes must not be written past the end of the input
+	val := testStruct{}
+	Fill(&val, data[:4], WithExhaustion(PRNGOnExhaustion))
+	assert.Equal(t, []byte{1, 2, 3, 4, 9, 9, 9, 9, 9, 9, 9, 9}, data)
+}
+
+func TestFill_PRNGOnExhaustion_Limit(t *testing.T) {
+	type node struct {
+		Value int `fuzz-int-range:"1,10"`
+		Next  *node
+	}
+
+	// A linked list keeps growing until the expansion limit is reached,
+	// then the remaining values are filled with their minimum values
+	val := node{}
+	Fill(&val, []byte{}, WithExhaustion(PRNGOnExhaustion), WithExpansionLimit(8*10))
+
+	length := 0
+	for n := &val; n != nil; n = n.Next {
+		assert.GreaterOrEqual(t, n.Value, 1)
+		assert.LessOrEqual(t, n.Value, 10)
+		length++
+	}
+	assert.Equal(t, 10, length)
+}
+
+func TestFill_Result(t *testing.T) {
+	type innerStruct struct {
+		BoolField  bool
+		SliceField []int8 `fuzz-slice-range:"2,2"`
+	}
+	type testStruct struct {
+		IntField    int
+		StringField string
+		MapField    map[uint8]bool `fuzz-map-range:"1,1"`
+		Inner       *innerStruct
+		Unreached   *innerStruct
+	}
+
+	c := newByteConsumer([]byte{})
+	// IntField
+	c.pushInt64(1, bytesForNative)
+	// StringField
+	c.pushString("abc")
+	// MapField size, key and value
+	c.pushInt64(1, bytesForNative)
+	c.pushUint64(7, bytesFor8)
+	c.pushBool(true)
+	// Inner.BoolField
+	c.pushBool(true)
+	// Inner.SliceField
+	c.pushInt64(2, bytesForNative)
+	c.pushInt64(1, bytesFor8)
+	c.pushInt64(2, bytesFor8)
+
+	result := FillResult{}
+	val := testStruct{}
+	Fill(&val, c.getRawBytes(), WithResult(&result))
+
+	assert.Equal(t, FillResult{
+		BytesConsumed:  len(c.getRawBytes()),
+		BytesLeft:      0,
+		BytesGenerated: 0,
+		ValuesSet: map[reflect.Kind]int{
+			reflect.Int:     1,
+			reflect.String:  1,
+			reflect.Map:     1,
+			reflect.Uint8:   1,
+			reflect.Bool:    2,
+			reflect.Pointer: 2,
+			reflect.Slice:   1,
+			reflect.Int8:    2,
+		},
+		SliceLengths: map[string]int{
+			"*(testStruct).Inner(*innerStruct).SliceField ([]int8)": 2,
+		},
+		MapLengths: map[string]int{
+			"*(testStruct).MapField (map[uint8]bool)": 1,
+		},
+		Truncated:   true,
+		TruncatedAt: "*(testStruct).Unreached (*innerStruct)",
+	}, result)
+}
+
+func TestFill_Result_BytesLeft(t *testing.T) {
+	type testStruct struct {
+		IntField int8
+	}
+
+	result := FillResult{}
+	val := testStruct{}
+	Fill(&val, []byte{1, 2, 3}, WithResult(&result))
+
+	assert.Equal(t, 1, result.BytesConsumed)
+	assert.Equal(t, 2, result.BytesLeft)
+	assert.False(t, result.Truncated)
+	assert.Equal(t, "", result.TruncatedAt)
+}
+
+func TestFillPrefix(t *testing.T) {
+	type testStruct struct {
+		IntField    int16
+		StringField string `fuzz-string-range:"0,10"`
+	}
+
+	c := newByteConsumer([]byte{})
+	c.pushInt64(1, bytesFor16)
+	c.pushString("abc")
+	c.pushBytes([]byte{1, 2, 3})
+
+	val := testStruct{}
+	rest := FillPrefix(&val, c.getRawBytes())
+
+	assert.Equal(t, testStruct{IntField: 1, StringField: "abc"}, val)
+	assert.Equal(t, []byte{1, 2, 3}, rest)
+
+	// If the bytes run out there is nothing left over
+	rest = FillPrefix(&val, []byte{1})
+	assert.Empty(t, rest)
+}
+
+func TestFillAll(t *testing.T) {
+	type configStruct struct {
+		Size uint8
+	}
+	type stateStruct struct {
+		Values []int16 `fuzz-slice-range:"2,2"`
+	}
+	type opStruct struct {
+		Op int8
+	}
+
+	c := newByteConsumer([]byte{})
+	// configStruct
+	c.pushUint64(3, bytesFor8)
+	// stateStruct
+	c.pushInt64(2, bytesForNative)
+	c.pushInt64(-1, bytesFor16)
+	c.pushInt64(-2, bytesFor16)
+	// ops
+	c.pushInt64(5, bytesFor8)
+	c.pushInt64(6, bytesFor8)
+	c.pushInt64(7, bytesFor8)
+
+	config := configStruct{}
+	state := stateStruct{}
+	ops := []opStruct{}
+	rest := FillAll(c.getRawBytes(), []any{&config, &state, &ops})
+
+	// Each root is filled from the bytes left over by the previous root
+	assert.Equal(t, configStruct{Size: 3}, config)
+	assert.Equal(t, stateStruct{Values: []int16{-1, -2}}, state)
+	assert.Equal(t, []opStruct{{5}, {6}, {7}}, ops)
+	assert.Empty(t, rest)
+}
+
+func TestFillAll_Options(t *testing.T) {
+	type rangedStruct struct {
+		Value int `fuzz-int-range:"5,9"`
+	}
+
+	first := rangedStruct{}
+	second := rangedStruct{}
+	rest := FillAll([]byte{}, []any{&first, &second}, WithExhaustion(MinimumOnExhaustion))
+
+	// The options are used for every root
+	assert.Equal(t, rangedStruct{Value: 5}, first)
+	assert.Equal(t, rangedStruct{Value: 5}, second)
+	assert.Empty(t, rest)
+}
+
+func TestFillAll_SharesConsumer(t *testing.T) {
+	type valueStruct struct {
+		Value uint64
+	}
+
+	// Once the bytes run out the generated bytes carry on from one root
+	// to the next, rather than starting again for each root
+	first := valueStruct{}
+	second := valueStruct{}
+	rest := FillAll([]byte{}, []any{&first, &second}, WithExhaustion(PRNGOnExhaustion))
+	assert.NotEqual(t, first, second)
+	assert.NotZero(t, second.Value)
+	assert.Empty(t, rest)
+
+	// With bit packing the bools of both roots are drawn from one byte
+	type boolStruct struct {
+		A bool
+		B bool
+	}
+	bools := []boolStruct{{}, {}}
+	rest = FillAll([]byte{0b0110, 0xFF}, []any{&bools[0], &bools[1]}, WithBitPacking(true))
+	assert.Equal(t, []boolStruct{{A: false, B: true}, {A: true, B: false}}, bools)
+	assert.Equal(t, []byte{0xFF}, rest)
+}
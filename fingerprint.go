@@ -0,0 +1,241 @@
+package fuzzhelper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var _ valueVisitor = &layoutVisitor{}
+
+// The layoutVisitor builds a description of the order and way in which Fill
+// consumes bytes for a type. Field names are not included, so renaming a
+// field doesn't change the layout, but reordering fields does (unless they
+// have fuzz-id tags).
+type layoutVisitor struct {
+	builder *strings.Builder
+	// Interface option types currently being described, used to avoid
+	// infinite recursion
+	inProgress map[reflect.Type]bool
+}
+
+// Fingerprint returns a fingerprint of the byte layout Fill uses for root.
+//
+// If the fingerprint of a type changes, then inputs (such as a fuzz corpus)
+// used to fill the old version of the type will produce different values
+// when used to fill the new version. Storing the fingerprint in a test
+// allows these changes to be detected.
+//
+// Options which change the byte layout, such as WithFormat, also change the
+// fingerprint.
+func Fingerprint(root any, opts ...Option) string {
+	config := newFillConfig(opts)
+
+	rootType := reflect.TypeOf(root)
+	if rootType.Kind() != reflect.Pointer {
+		rootType = reflect.PointerTo(rootType)
+	}
+
+	layout := strings.Join(config.layoutOptions(), "") + layoutString(rootType, map[reflect.Type]bool{})
+	sum := sha256.Sum256([]byte(layout))
+	return hex.EncodeToString(sum[:16])
+}
+
+// A fillConfig field which changes the byte layout. The line is added to
+// the layout when set, options left at their default add nothing, so
+// adding a new option doesn't change existing fingerprints.
+type layoutOption struct {
+	field string
+	set   bool
+	line  string
+}
+
+// The fillConfig fields which don't change the byte layout. The exhaustion
+// policy and expansion limit only affect values filled once the input has
+// run out.
+var nonLayoutFields = []string{"exhaustion", "expansionLimit", "result"}
+
+// Returns every fillConfig field which changes the byte layout, in the
+// order their lines appear in the layout
+func (config fillConfig) layoutFields() []layoutOption {
+	return []layoutOption{
+		{field: "nilPercent", set: config.nilPercent != 0, line: fmt.Sprintf("nil:%d\n", config.nilPercent)},
+		{field: "aliasing", set: config.aliasing, line: "alias\n"},
+		{field: "distinctMapKeys", set: config.distinctMapKeys, line: "distinct\n"},
+		{field: "maxDepth", set: config.maxDepth != 0, line: fmt.Sprintf("depth:%d\n", config.maxDepth)},
+		{field: "edgeBias", set: config.edgeBias, line: "bias:edges\n"},
+		{field: "packBits", set: config.packBits, line: "bits:packed\n"},
+		{field: "byteChunks", set: config.byteChunks, line: "bytes:chunks\n"},
+		{field: "traversal", set: config.traversal != BreadthFirst, line: fmt.Sprintf("traversal:%d\n", config.traversal)},
+		{field: "layout", set: config.layout != InterleavedLayout, line: fmt.Sprintf("layout:%d\n", config.layout)},
+		{field: "format", set: config.format != NativeFormat, line: fmt.Sprintf("format:%d\n", config.format)},
+	}
+}
+
+// Returns the lines added to the layout by the options which are set
+func (config fillConfig) layoutOptions() []string {
+	lines := []string{}
+	for _, option := range config.layoutFields() {
+		if option.set {
+			lines = append(lines, option.line)
+		}
+	}
+	return lines
+}
+
+func layoutString(rootType reflect.Type, inProgress map[reflect.Type]bool) string {
+	v := &layoutVisitor{
+		builder:    &strings.Builder{},
+		inProgress: inProgress,
+	}
+	root := reflect.New(rootType.Elem())
+	// Each traversal order visits the same values, the order is added to
+	// the fingerprint by Fingerprint
+	visitRoot(v, root.Interface(), newByteConsumer([]byte{1}), BreadthFirst)
+	return v.builder.String()
+}
+
+func (v *layoutVisitor) line(tags fuzzTags, path valuePath, description string) {
+	fmt.Fprintf(v.builder, "%d %s %s\n", len(path.names), description, tags.layout)
+}
+
+func (v *layoutVisitor) scalar(value reflect.Value, tags fuzzTags, path valuePath) {
+	if !value.CanSet() {
+		// Fill doesn't consume any bytes for values it can't set
+		return
+	}
+	v.line(tags, path, fmt.Sprintf("%s:%d", value.Kind(), value.Type().Size()))
+}
+
+func (v *layoutVisitor) visitBool(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	v.scalar(value, tags, path)
+}
+
+func (v *layoutVisitor) visitInt(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	v.scalar(value, tags, path)
+}
+
+func (v *layoutVisitor) visitUint(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	v.scalar(value, tags, path)
+}
+
+func (v *layoutVisitor) visitUintptr(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *layoutVisitor) visitFloat(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	v.scalar(value, tags, path)
+}
+
+func (v *layoutVisitor) visitComplex(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *layoutVisitor) visitArray(value reflect.Value, tags fuzzTags, path valuePath) {
+	v.line(tags, path, fmt.Sprintf("array:%d", value.Len()))
+}
+
+func (v *layoutVisitor) visitPointer(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
+	if !value.CanSet() {
+		return true
+	}
+
+	v.line(tags, path, "pointer")
+	value.Set(reflect.New(value.Type().Elem()))
+	return true
+}
+
+func (v *layoutVisitor) visitSlice(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) (from, to int) {
+	if !value.CanSet() || value.Len() != 0 {
+		// As with Describe, we only visit a single element of an
+		// unbounded slice
+		return 0, 0
+	}
+
+	v.line(tags, path, "slice")
+	value.Set(reflect.MakeSlice(value.Type(), 1, 1))
+	return 0, 1
+}
+
+func (v *layoutVisitor) visitBytes(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
+	// Copied bytes are consumed just as bytes filled one at a time, the
+	// tags in the layout tell them apart
+	return false
+}
+
+func (v *layoutVisitor) visitMap(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) int {
+	if !value.CanSet() {
+		return 0
+	}
+
+	v.line(tags, path, "map")
+	value.Set(reflect.MakeMapWithSize(value.Type(), 1))
+	return 1
+}
+
+func (v *layoutVisitor) visitElements(value reflect.Value, tags fuzzTags, path valuePath) {
+}
+
+func (v *layoutVisitor) visitMapKey(mapValue, key reflect.Value, tags fuzzTags) {
+}
+
+func (v *layoutVisitor) mapEntry(mapValue reflect.Value, index int) (key, val reflect.Value) {
+	return newMapEntry(mapValue)
+}
+
+func (v *layoutVisitor) visitChan(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *layoutVisitor) visitFunc(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *layoutVisitor) visitInterface(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) bool {
+	if !value.CanSet() || !tags.interfaceValues.wasSet {
+		// Fill doesn't consume any bytes for this interface
+		return false
+	}
+
+	v.line(tags, path, "interface")
+
+	// Each of the interface options is described in full
+	for _, option := range tags.interfaceValues.value {
+		optionType := reflect.TypeOf(option)
+		if v.inProgress[optionType] {
+			fmt.Fprintf(v.builder, "option %s recursion\n", optionType)
+			continue
+		}
+
+		v.inProgress[optionType] = true
+		fmt.Fprintf(v.builder, "option %s {\n%s}\n", optionType, layoutString(reflect.PointerTo(optionType), v.inProgress))
+		delete(v.inProgress, optionType)
+	}
+
+	return false
+}
+
+func (v *layoutVisitor) visitString(value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) {
+	v.scalar(value, tags, path)
+}
+
+func (v *layoutVisitor) visitStruct(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	if path.containsType(value.Type()) {
+		v.line(tags, path, "recursion")
+		return false
+	}
+
+	return true
+}
+
+func (v *layoutVisitor) visitUnsafePointer(value reflect.Value, tags fuzzTags, path valuePath) {
+	// Not supported by Fill, consumes no bytes
+}
+
+func (v *layoutVisitor) visitExhausted(value reflect.Value, tags fuzzTags, path valuePath) bool {
+	// The layoutVisitor never consumes any bytes, so it should never be
+	// exhausted
+	return false
+}
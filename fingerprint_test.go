@@ -0,0 +1,191 @@
+package fuzzhelper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type layoutOriginal struct {
+	IntField    int
+	StringField string `fuzz-string-range:"1,5"`
+	SliceField  []uint8
+}
+
+type layoutRenamed struct {
+	RenamedInt    int
+	RenamedString string `fuzz-string-range:"1,5"`
+	RenamedSlice  []uint8
+}
+
+type layoutReordered struct {
+	StringField string `fuzz-string-range:"1,5"`
+	IntField    int
+	SliceField  []uint8
+}
+
+type layoutRetagged struct {
+	IntField    int
+	StringField string `fuzz-string-range:"1,6"`
+	SliceField  []uint8
+}
+
+type layoutIds struct {
+	IntField    int     `fuzz-id:"1"`
+	StringField string  `fuzz-id:"2" fuzz-string-range:"1,5"`
+	SliceField  []uint8 `fuzz-id:"3"`
+}
+
+type layoutIdsReordered struct {
+	SliceField  []uint8 `fuzz-id:"3"`
+	IntField    int     `fuzz-id:"1"`
+	StringField string  `fuzz-id:"2" fuzz-string-range:"1,5"`
+	// Unsupported fields don't consume any bytes and don't change the layout
+	ChanField chan int
+}
+
+func TestFingerprint(t *testing.T) {
+	original := Fingerprint(&layoutOriginal{})
+
+	// Fingerprints are stable
+	assert.Equal(t, original, Fingerprint(&layoutOriginal{}))
+	assert.Equal(t, original, Fingerprint(layoutOriginal{}))
+
+	// Renaming fields doesn't change the layout
+	assert.Equal(t, original, Fingerprint(&layoutRenamed{}))
+
+	// Reordering fields does change the layout
+	assert.NotEqual(t, original, Fingerprint(&layoutReordered{}))
+
+	// Changing tags changes the layout
+	assert.NotEqual(t, original, Fingerprint(&layoutRetagged{}))
+
+	// Fields with ids can be reordered without changing the layout
+	assert.Equal(t, original, Fingerprint(&layoutIds{}))
+	assert.Equal(t, original, Fingerprint(&layoutIdsReordered{}))
+}
+
+type layoutOptionsA struct {
+	Op interfaceDemo `fuzz-interface-method:"Options"`
+}
+
+func (s *layoutOptionsA) Options() []interfaceDemo {
+	return []interfaceDemo{&interfaceDemoA{}, &interfaceDemoB{}}
+}
+
+type layoutOptionsB struct {
+	Op interfaceDemo `fuzz-interface-method:"Options"`
+}
+
+func (s *layoutOptionsB) Options() []interfaceDemo {
+	return []interfaceDemo{&interfaceDemoB{}, &interfaceDemoA{}}
+}
+
+func TestFingerprint_InterfaceOptions(t *testing.T) {
+	// The order of interface options is part of the layout
+	assert.NotEqual(t, Fingerprint(&layoutOptionsA{}), Fingerprint(&layoutOptionsB{}))
+}
+
+func TestFingerprint_Options(t *testing.T) {
+	original := Fingerprint(&layoutOriginal{})
+
+	testCases := []struct {
+		name   string
+		option Option
+		// Set if the option changes the layout
+		changes bool
+	}{
+		{name: "nil percent", option: WithNilPercent(10), changes: true},
+		{name: "aliasing", option: WithAliasing(true), changes: true},
+		{name: "distinct map keys", option: WithDistinctMapKeys(true), changes: true},
+		{name: "max depth", option: WithMaxDepth(3), changes: true},
+		{name: "edge bias", option: WithEdgeBias(true), changes: true},
+		{name: "bit packing", option: WithBitPacking(true), changes: true},
+		{name: "traversal", option: WithTraversal(DepthFirst), changes: true},
+		{name: "split layout", option: WithLayout(SplitLayout), changes: true},
+		{name: "compact format", option: WithFormat(CompactFormat), changes: true},
+		{name: "exhaustion", option: WithExhaustion(PRNGOnExhaustion)},
+		{name: "expansion limit", option: WithExpansionLimit(10)},
+		{name: "result", option: WithResult(&FillResult{})},
+		// Options left at their default don't change the layout
+		{name: "default nil percent", option: WithNilPercent(0)},
+		{name: "default traversal", option: WithTraversal(BreadthFirst)},
+		{name: "default format", option: WithFormat(NativeFormat)},
+	}
+
+	fingerprints := map[string]string{}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fingerprint := Fingerprint(&layoutOriginal{}, testCase.option)
+			if !testCase.changes {
+				assert.Equal(t, original, fingerprint)
+				return
+			}
+			assert.NotEqual(t, original, fingerprint)
+			// Each option changes the layout in its own way
+			for name, other := range fingerprints {
+				assert.NotEqual(t, other, fingerprint, "same fingerprint as %s", name)
+			}
+			fingerprints[testCase.name] = fingerprint
+		})
+	}
+}
+
+// Every fillConfig field either changes the layout, or is known not to
+func TestFingerprint_AllOptionFields(t *testing.T) {
+	fields := []string{}
+	for _, option := range newFillConfig(nil).layoutFields() {
+		fields = append(fields, option.field)
+	}
+	fields = append(fields, nonLayoutFields...)
+
+	configFields := []string{}
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(fillConfig{})) {
+		configFields = append(configFields, field.Name)
+	}
+	assert.ElementsMatch(t, configFields, fields)
+}
+
+func TestFill_FieldIds(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	c.pushInt64(1, bytesForNative)
+	// String length of 2 is fitted to 3 by the 1,5 range
+	c.pushInt64(2, bytesForNative)
+	c.pushBytes([]byte("abc"))
+	c.pushUint64(2, bytesFor8)
+
+	original := layoutOriginal{}
+	Fill(&original, c.getRawBytes())
+
+	reordered := layoutIdsReordered{}
+	Fill(&reordered, c.getRawBytes())
+
+	// Fields are filled in id order, not declaration order
+	assert.Equal(t, layoutIdsReordered{
+		IntField:    1,
+		StringField: "abc",
+		SliceField:  []uint8{2},
+	}, reordered)
+	assert.Equal(t, original.IntField, reordered.IntField)
+	assert.Equal(t, original.StringField, reordered.StringField)
+	assert.Equal(t, original.SliceField, reordered.SliceField)
+}
+
+type badDuplicateFieldId struct {
+	IntField    int    `fuzz-id:"1"`
+	StringField string `fuzz-id:"1"`
+}
+
+type badFieldId struct {
+	IntField int `fuzz-id:"one"`
+}
+
+func TestFill_BadFieldIds(t *testing.T) {
+	assert.PanicsWithError(t, "fuzzhelper.badDuplicateFieldId.IntField and fuzzhelper.badDuplicateFieldId.StringField have the same fuzz-id 1", func() {
+		Fill(&badDuplicateFieldId{}, []byte{1, 2, 3})
+	})
+	assert.PanicsWithError(t, `fuzzhelper.badFieldId.IntField has invalid fuzz-id "one", must be a non-negative integer`, func() {
+		Fill(&badFieldId{}, []byte{1, 2, 3})
+	})
+}
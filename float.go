@@ -0,0 +1,159 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The kinds of special float values which can be listed in a
+// fuzz-float-special tag
+var floatSpecialKinds = []string{"nan", "inf", "negzero", "subnormal"}
+
+type floatSpecialTag struct {
+	wasSet bool
+	kinds  []string
+}
+
+func newFloatSpecialTag(structVal reflect.Value, field reflect.StructField, tag string) floatSpecialTag {
+	valStr, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return floatSpecialTag{}
+	}
+
+	kinds := strings.Split(valStr, ",")
+	for _, kind := range kinds {
+		if !slices.Contains(floatSpecialKinds, kind) {
+			panic(fmt.Errorf("%s.%s has invalid %s %q, must be a comma separated list of %s", structVal.Type(), field.Name, tag, valStr, strings.Join(floatSpecialKinds, ", ")))
+		}
+	}
+
+	return floatSpecialTag{
+		wasSet: true,
+		kinds:  kinds,
+	}
+}
+
+// Returns the special values for a float, in the order their kinds are
+// listed in the tag. Subnormal values are subnormal for the float's type.
+func (t floatSpecialTag) values(value reflect.Value) []float64 {
+	smallest, largestSubnormal := math.SmallestNonzeroFloat64, math.Float64frombits(1<<52-1)
+	if value.Type().Size() == bytesFor32 {
+		smallest, largestSubnormal = math.SmallestNonzeroFloat32, float64(math.Float32frombits(1<<23-1))
+	}
+
+	values := []float64{}
+	for _, kind := range t.kinds {
+		switch kind {
+		case "nan":
+			values = append(values, math.NaN())
+		case "inf":
+			values = append(values, math.Inf(1), math.Inf(-1))
+		case "negzero":
+			values = append(values, math.Copysign(0, -1))
+		case "subnormal":
+			values = append(values, smallest, -smallest, largestSubnormal, -largestSubnormal)
+		}
+	}
+	return values
+}
+
+// Returns the index of val in values, or -1 if it isn't found. Unlike ==,
+// NaN matches NaN and 0 doesn't match -0.
+func floatIndex(values []float64, val float64) int {
+	return slices.IndexFunc(values, func(other float64) bool {
+		if math.IsNaN(val) {
+			return math.IsNaN(other)
+		}
+		return math.Float64bits(val) == math.Float64bits(other)
+	})
+}
+
+type floatDecimalsTag struct {
+	wasSet   bool
+	decimals int
+}
+
+// The largest number of decimal places a float64 can reliably hold
+const maxFloatDecimals = 15
+
+func newFloatDecimalsTag(structVal reflect.Value, field reflect.StructField, tag string) floatDecimalsTag {
+	valStr, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return floatDecimalsTag{}
+	}
+
+	decimals, err := strconv.Atoi(valStr)
+	if err != nil || decimals < 0 || decimals > maxFloatDecimals {
+		panic(fmt.Errorf("%s.%s has invalid %s %q, must be an integer from 0 to %d", structVal.Type(), field.Name, tag, valStr, maxFloatDecimals))
+	}
+
+	return floatDecimalsTag{
+		wasSet:   true,
+		decimals: decimals,
+	}
+}
+
+// Rounds val to the tagged number of decimal places, staying within r if it
+// is valid. A value of 0 decimal places produces integral floats.
+func (t floatDecimalsTag) round(val float64, r floatTagRange) float64 {
+	if !t.wasSet || math.IsNaN(val) || math.IsInf(val, 0) {
+		return val
+	}
+
+	scale := math.Pow10(t.decimals)
+	rounded := math.Round(val*scale) / scale
+	if math.IsInf(rounded, 0) || math.IsNaN(rounded) {
+		// val is too large to scale, and already has no decimal places
+		return val
+	}
+
+	if r.valid() {
+		if rounded < r.floatMin {
+			rounded = math.Ceil(r.floatMin*scale) / scale
+		}
+		if rounded > r.floatMax {
+			rounded = math.Floor(r.floatMax*scale) / scale
+		}
+		if !r.contains(rounded) {
+			// Rounding moved val into a gap between intervals
+			return val
+		}
+	}
+	return rounded
+}
+
+// Converts a float64 to float32 precision, staying within the interval of r
+// containing val if there is one, and it contains a float32 value. Without
+// this a value rounded to the nearest float32 could lie just outside of the
+// range.
+func fitFloat32(val float64, r floatTagRange) float64 {
+	fitted := float32(val)
+	if !r.valid() {
+		return float64(fitted)
+	}
+
+	for _, i := range r.parts() {
+		if val < i.lo || val > i.hi {
+			continue
+		}
+
+		lo, hi := float32(i.lo), float32(i.hi)
+		if float64(lo) < i.lo {
+			lo = math.Nextafter32(lo, float32(math.Inf(1)))
+		}
+		if float64(hi) > i.hi {
+			hi = math.Nextafter32(hi, float32(math.Inf(-1)))
+		}
+		if lo > hi {
+			// There is no float32 value within the interval
+			break
+		}
+		return float64(min(max(fitted, lo), hi))
+	}
+
+	return float64(fitted)
+}
@@ -0,0 +1,176 @@
+package fuzzhelper
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type floatSpecialStruct struct {
+	NaNField       float64 `fuzz-float-special:"nan"`
+	InfField       float64 `fuzz-float-special:"nan,inf" fuzz-float-range:"0,1"`
+	NegZeroField   float64 `fuzz-float-special:"negzero"`
+	SubnormalField float32 `fuzz-float-special:"subnormal"`
+	NormalField    float64 `fuzz-float-special:"nan,inf,negzero,subnormal" fuzz-float-range:"0,10"`
+}
+
+func TestFill_FloatSpecial(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// NaNField
+	c.pushSelector(0)
+	// InfField, negative infinity even though there is a range
+	c.pushSelector(2)
+	// NegZeroField
+	c.pushSelector(0)
+	// SubnormalField, the largest float32 subnormal
+	c.pushSelector(2)
+	// NormalField, a normal value
+	c.pushNoSelector()
+	c.pushFloat64(2.5, bytesFor64)
+
+	val := floatSpecialStruct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.True(t, math.IsNaN(val.NaNField))
+	assert.Equal(t, math.Inf(-1), val.InfField)
+	assert.Equal(t, 0.0, val.NegZeroField)
+	assert.True(t, math.Signbit(val.NegZeroField))
+	assert.Equal(t, math.Float32frombits(1<<23-1), val.SubnormalField)
+	assert.Equal(t, 2.5, val.NormalField)
+}
+
+func TestEncode_FloatSpecial(t *testing.T) {
+	val := floatSpecialStruct{
+		NaNField:       math.NaN(),
+		InfField:       math.Inf(1),
+		NegZeroField:   math.Copysign(0, -1),
+		SubnormalField: -math.SmallestNonzeroFloat32,
+		NormalField:    0,
+	}
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+
+	filled := floatSpecialStruct{}
+	Fill(&filled, encoded)
+
+	assert.True(t, math.IsNaN(filled.NaNField))
+	assert.Equal(t, math.Inf(1), filled.InfField)
+	assert.True(t, math.Signbit(filled.NegZeroField))
+	assert.Equal(t, float32(-math.SmallestNonzeroFloat32), filled.SubnormalField)
+	// Positive zero is not a special value
+	assert.Equal(t, 0.0, filled.NormalField)
+	assert.False(t, math.Signbit(filled.NormalField))
+}
+
+type floatDecimalsStruct struct {
+	PriceField    float64 `fuzz-float-decimals:"2" fuzz-float-range:"0.001,100"`
+	IntegralField float32 `fuzz-float-decimals:"0"`
+	UnrangedField float64 `fuzz-float-decimals:"1"`
+}
+
+func TestFill_FloatDecimals(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	c.pushFloat64(12.34567, bytesFor64)
+	c.pushFloat64(-7.5, bytesFor32)
+	c.pushFloat64(math.MaxFloat64, bytesFor64)
+
+	val := floatDecimalsStruct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.Equal(t, floatDecimalsStruct{
+		PriceField:    12.35,
+		IntegralField: -8,
+		UnrangedField: math.MaxFloat64,
+	}, val)
+
+	// Rounding never leaves the range
+	c = newByteConsumer([]byte{})
+	c.pushFloat64(0, bytesFor64)
+
+	val = floatDecimalsStruct{}
+	Fill(&val, c.getRawBytes())
+	assert.Equal(t, 0.01, val.PriceField)
+}
+
+func TestFill_Float32Range(t *testing.T) {
+	type float32Struct struct {
+		Field float32 `fuzz-float-range:"0.1,0.3"`
+	}
+
+	// float32(0.1) is larger than 0.1, but float32(0.3) is also larger
+	// than 0.3 and must not be produced
+	require.Greater(t, float64(float32(0.3)), 0.3)
+
+	c := newByteConsumer([]byte{})
+	c.pushFloat64(math.Inf(1), bytesFor32)
+
+	val := float32Struct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.LessOrEqual(t, float64(val.Field), 0.3)
+	assert.Equal(t, math.Nextafter32(float32(0.3), 0), val.Field)
+}
+
+type badFloatSpecialTag struct {
+	FloatField float64 `fuzz-float-special:"nan,huge"`
+}
+
+type badFloatDecimalsTag struct {
+	FloatField float64 `fuzz-float-decimals:"-1"`
+}
+
+func TestFill_BadFloatTags(t *testing.T) {
+	assert.PanicsWithError(t, `fuzzhelper.badFloatSpecialTag.FloatField has invalid fuzz-float-special "nan,huge", must be a comma separated list of nan, inf, negzero, subnormal`, func() {
+		Fill(&badFloatSpecialTag{}, []byte{1})
+	})
+	assert.PanicsWithError(t, `fuzzhelper.badFloatDecimalsTag.FloatField has invalid fuzz-float-decimals "-1", must be an integer from 0 to 15`, func() {
+		Fill(&badFloatDecimalsTag{}, []byte{1})
+	})
+}
+
+func TestFill_FloatRangeSingleValueInterval(t *testing.T) {
+	type testStruct struct {
+		FloatField float64 `fuzz-float-range:"0,10|100,100|200,300"`
+	}
+
+	// Each interval is chosen before a value is fitted into it, so the
+	// single value 100 is reached as often as the other intervals
+	r := rand.New(rand.NewPCG(1, 2))
+	counts := map[string]int{}
+	for range 3000 {
+		bytes := make([]byte, 16)
+		for i := range bytes {
+			bytes[i] = byte(r.Uint32())
+		}
+		val := testStruct{}
+		Fill(&val, bytes)
+
+		switch {
+		case val.FloatField == 100:
+			counts["single"]++
+		case val.FloatField >= 0 && val.FloatField <= 10:
+			counts["low"]++
+		case val.FloatField >= 200 && val.FloatField <= 300:
+			counts["high"]++
+		default:
+			t.Fatalf("%g is outside of the range", val.FloatField)
+		}
+	}
+	for _, name := range []string{"low", "single", "high"} {
+		assert.InDelta(t, 1000, counts[name], 150, name)
+	}
+
+	// Values in every interval can be encoded
+	for _, f := range []float64{0, 5, 100, 200, 250} {
+		val := testStruct{FloatField: f}
+		encoded, err := Encode(&val)
+		require.NoError(t, err)
+		filled := testStruct{}
+		Fill(&filled, encoded)
+		assert.Equal(t, val, filled)
+	}
+}
@@ -0,0 +1,93 @@
+package fuzzhelper
+
+import "reflect"
+
+// ByteFormat controls how many bytes Fill consumes for each value.
+type ByteFormat int
+
+const (
+	// NativeFormat consumes the native int size for every choice between
+	// method options and every length, and the full size of the type for
+	// every number. This is the default format.
+	NativeFormat ByteFormat = iota
+
+	// CompactFormat consumes only as many bytes as are needed for the
+	// number of options, or the span of a bounded range. Choosing between
+	// up to 256 options, or a length from a range of up to 256 values,
+	// consumes a single byte. Shorter inputs are easier for the fuzzer to
+	// mutate effectively and easier for people to read.
+	CompactFormat
+)
+
+// WithFormat sets the byte format used to fill values.
+func WithFormat(format ByteFormat) Option {
+	return func(config *fillConfig) {
+		config.format = format
+	}
+}
+
+// ByteLayout controls where in the input Fill finds the bytes for each value.
+type ByteLayout int
+
+const (
+	// InterleavedLayout consumes the bytes for every value in order, from
+	// the start of the input. This is the default layout.
+	InterleavedLayout ByteLayout = iota
+
+	// SplitLayout consumes control values, i.e. bools, the lengths of
+	// slices, maps and strings and the choice between method or interface
+	// options, from the end of the input. All other values, such as the
+	// bytes of strings and numbers, are consumed from the start. This is
+	// the layout used by libFuzzer's FuzzedDataProvider, a mutation of
+	// the structure of a value leaves the content of the value in place.
+	SplitLayout
+)
+
+// WithLayout sets the byte layout used to fill values.
+func WithLayout(layout ByteLayout) Option {
+	return func(config *fillConfig) {
+		config.layout = layout
+	}
+}
+
+// Returns the number of bytes needed to represent span different values. A
+// span of 0 indicates the full range of a uint64.
+func bytesForSpan(span uint64) uintptr {
+	switch {
+	case span == 0:
+		return bytesFor64
+	case span <= 1<<8:
+		return bytesFor8
+	case span <= 1<<16:
+		return bytesFor16
+	case span <= 1<<32:
+		return bytesFor32
+	default:
+		return bytesFor64
+	}
+}
+
+// Returns the number of bytes consumed to choose between n options
+func (f ByteFormat) choiceSize(n int) uintptr {
+	if f == CompactFormat {
+		return bytesForSpan(uint64(n))
+	}
+	return bytesForNative
+}
+
+// Returns the number of bytes consumed to choose a length from r
+func (f ByteFormat) lengthSize(r lengthTagRange) uintptr {
+	if f == CompactFormat {
+		return bytesForSpan(r.uintRange.span())
+	}
+	return bytesForNative
+}
+
+// Returns the number of bytes consumed for a number with the range span. A
+// span of 0 indicates that there is no range.
+func (f ByteFormat) numberSize(value reflect.Value, span uint64) uintptr {
+	if f == CompactFormat && span != 0 {
+		return min(bytesForSpan(span), value.Type().Size())
+	}
+	return value.Type().Size()
+}
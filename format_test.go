@@ -0,0 +1,180 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type compactStruct struct {
+	IntField       int32         `fuzz-int-range:"-100,100"`
+	WideIntField   int64         `fuzz-int-range:"0,100000"`
+	UintField      uint64        `fuzz-uint-range:"1,256"`
+	RawField       int16         // no range, consumes the full size
+	StringField    string        `fuzz-string-method:"StringOptions"`
+	LengthField    string        `fuzz-string-range:"2,5"`
+	SliceField     []uint8       `fuzz-slice-range:"0,300"`
+	InterfaceField interfaceDemo `fuzz-interface-method:"InterfaceOptions"`
+}
+
+func (s *compactStruct) StringOptions() []string {
+	return []string{"a", "b", "c"}
+}
+
+func (s *compactStruct) InterfaceOptions() []interfaceDemo {
+	return []interfaceDemo{&interfaceDemoB{}, &interfaceDemoC{}}
+}
+
+func TestFill_CompactFormat(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// IntField, offset from -100 in one byte
+	c.pushUint64(105, bytesFor8)
+	// WideIntField, span needs 4 bytes
+	c.pushUint64(99_999, bytesFor32)
+	// UintField, offset from 1 in one byte
+	c.pushUint64(255, bytesFor8)
+	// RawField
+	c.pushInt64(-7, bytesFor16)
+	// StringField choice in one byte
+	c.pushUint64(2, bytesFor8)
+	// LengthField length offset in one byte, followed by the string
+	c.pushUint64(1, bytesFor8)
+	c.pushBytes([]byte("xyz"))
+	// SliceField length in two bytes, followed by the elements
+	c.pushUint64(2, bytesFor16)
+	c.pushUint64(7, bytesFor8)
+	c.pushUint64(8, bytesFor8)
+	// InterfaceField choice in one byte
+	c.pushUint64(1, bytesFor8)
+
+	val := compactStruct{}
+	Fill(&val, c.getRawBytes(), WithFormat(CompactFormat))
+
+	assert.Equal(t, compactStruct{
+		IntField:       5,
+		WideIntField:   99_999,
+		UintField:      256,
+		RawField:       -7,
+		StringField:    "c",
+		LengthField:    "xyz",
+		SliceField:     []uint8{7, 8},
+		InterfaceField: &interfaceDemoC{},
+	}, val)
+}
+
+func TestEncode_CompactFormat(t *testing.T) {
+	val := compactStruct{
+		IntField:       -100,
+		WideIntField:   100_000,
+		UintField:      1,
+		RawField:       300,
+		StringField:    "b",
+		LengthField:    "hello",
+		SliceField:     []uint8{1, 2, 3},
+		InterfaceField: &interfaceDemoB{},
+	}
+
+	compact, err := Encode(&val, WithFormat(CompactFormat))
+	require.NoError(t, err)
+	native, err := Encode(&val)
+	require.NoError(t, err)
+	assert.Less(t, len(compact), len(native))
+
+	filled := compactStruct{}
+	Fill(&filled, compact, WithFormat(CompactFormat))
+	assert.Equal(t, val, filled)
+
+	filled = compactStruct{}
+	Fill(&filled, native)
+	assert.Equal(t, val, filled)
+}
+
+func TestFingerprint_Format(t *testing.T) {
+	assert.Equal(t, Fingerprint(&compactStruct{}), Fingerprint(&compactStruct{}, WithFormat(NativeFormat)))
+	assert.NotEqual(t, Fingerprint(&compactStruct{}), Fingerprint(&compactStruct{}, WithFormat(CompactFormat)))
+}
+
+func TestBytesForSpan(t *testing.T) {
+	assert.Equal(t, uintptr(1), bytesForSpan(1))
+	assert.Equal(t, uintptr(1), bytesForSpan(256))
+	assert.Equal(t, uintptr(2), bytesForSpan(257))
+	assert.Equal(t, uintptr(2), bytesForSpan(1<<16))
+	assert.Equal(t, uintptr(4), bytesForSpan(1<<16+1))
+	assert.Equal(t, uintptr(4), bytesForSpan(1<<32))
+	assert.Equal(t, uintptr(8), bytesForSpan(1<<32+1))
+	// A span of 0 is the full uint64 range
+	assert.Equal(t, uintptr(8), bytesForSpan(0))
+}
+
+type splitStruct struct {
+	Flag  bool
+	Name  string  `fuzz-string-range:"0,10"`
+	Mode  string  `fuzz-string-method:"Modes"`
+	Value uint16  `fuzz-uint-range:"0,1000"`
+	Items []uint8 `fuzz-slice-range:"0,5"`
+}
+
+func (s *splitStruct) Modes() []string {
+	return []string{"a", "b", "c"}
+}
+
+func TestFill_SplitLayout(t *testing.T) {
+	opts := []Option{WithLayout(SplitLayout), WithFormat(CompactFormat)}
+
+	// Content, from the start
+	input := []byte{'h', 'i', 0xe8, 0x03, 7, 8, 9}
+	// Control values, read backwards from the end
+	input = append(input, 3, 2, 2, 1)
+
+	val := splitStruct{}
+	Fill(&val, input, opts...)
+	assert.Equal(t, splitStruct{
+		Flag:  true,
+		Name:  "hi",
+		Mode:  "c",
+		Value: 1000,
+		Items: []uint8{7, 8, 9},
+	}, val)
+
+	encoded, err := Encode(&val, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, input, encoded)
+}
+
+func TestFill_SplitLayout_StableContent(t *testing.T) {
+	opts := []Option{WithLayout(SplitLayout), WithFormat(CompactFormat)}
+
+	val := splitStruct{Name: "hello", Mode: "a", Value: 500, Items: []uint8{1, 2}}
+	input, err := Encode(&val, opts...)
+	require.NoError(t, err)
+
+	// Changing a control byte, here the choice of Mode, leaves all of
+	// the content in place
+	input[len(input)-3] = 1
+	filled := splitStruct{}
+	Fill(&filled, input, opts...)
+
+	val.Mode = "b"
+	assert.Equal(t, val, filled)
+}
+
+func TestFill_SplitLayout_RoundTrip(t *testing.T) {
+	for _, packBits := range []bool{false, true} {
+		opts := []Option{WithLayout(SplitLayout), WithBitPacking(packBits)}
+
+		val := splitStruct{
+			Flag:  true,
+			Name:  "outer",
+			Mode:  "b",
+			Value: 3,
+			Items: []uint8{1},
+		}
+		input, err := Encode(&val, opts...)
+		require.NoError(t, err)
+
+		filled := splitStruct{}
+		Fill(&filled, input, opts...)
+		assert.Equal(t, val, filled)
+	}
+}
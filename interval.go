@@ -0,0 +1,151 @@
+package fuzzhelper
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// An interval of values from lo to hi inclusive
+type interval[T int64 | uint64 | float64] struct {
+	lo T
+	hi T
+}
+
+// Parses the value of a range tag. A range is one or more "min,max"
+// intervals separated by "|". If min or max are omitted they are replaced by
+// openMin, or openMax(min). Returns false if the range can't be parsed.
+//
+// A range made of several intervals must have min <= max for each interval.
+// The intervals are returned sorted, with overlapping intervals merged. A
+// range with a single interval is returned as is.
+func parseIntervals[T int64 | uint64 | float64](valStr string, parse func(string) (T, error), openMin T, openMax func(T) T) ([]interval[T], bool) {
+	intervals := []interval[T]{}
+	for _, intervalStr := range strings.Split(valStr, "|") {
+		parts := strings.Split(intervalStr, ",")
+		if len(parts) != 2 {
+			//println("bad min max tag", valStr)
+			return nil, false
+		}
+
+		lo := openMin
+		if parts[0] != "" {
+			val, err := parse(parts[0])
+			if err != nil {
+				//println("bad min tag value", valStr)
+				return nil, false
+			}
+			lo = val
+		}
+
+		hi := openMax(lo)
+		if parts[1] != "" {
+			val, err := parse(parts[1])
+			if err != nil {
+				//println("bad max tag value", valStr)
+				return nil, false
+			}
+			hi = val
+		}
+
+		intervals = append(intervals, interval[T]{lo: lo, hi: hi})
+	}
+
+	if len(intervals) == 1 {
+		return intervals, true
+	}
+
+	for _, i := range intervals {
+		if i.lo > i.hi {
+			return nil, false
+		}
+	}
+
+	slices.SortFunc(intervals, func(a, b interval[T]) int {
+		return cmp.Compare(a.lo, b.lo)
+	})
+
+	merged := intervals[:1]
+	for _, i := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if i.lo <= last.hi {
+			last.hi = max(last.hi, i.hi)
+			continue
+		}
+		merged = append(merged, i)
+	}
+	return merged, true
+}
+
+// Narrows each interval to [lo, hi], dropping intervals which lie outside
+// it. Returns false if no intervals are left.
+func clampIntervals[T int64 | uint64 | float64](intervals []interval[T], lo, hi T) ([]interval[T], bool) {
+	clamped := []interval[T]{}
+	for _, i := range intervals {
+		if i.hi < lo || i.lo > hi {
+			continue
+		}
+		clamped = append(clamped, interval[T]{lo: max(i.lo, lo), hi: min(i.hi, hi)})
+	}
+	return clamped, len(clamped) > 0
+}
+
+// Returns the number of values in the intervals. Returns 0 if the intervals
+// cover every 64 bit value. The intervals must not overlap.
+func intervalsSpan[T int64 | uint64](intervals []interval[T]) uint64 {
+	span := uint64(0)
+	for _, i := range intervals {
+		span += uint64(i.hi) - uint64(i.lo) + 1
+	}
+	return span
+}
+
+// Maps offset onto the values of the intervals, taken in ascending order.
+// Offsets larger than the number of values wrap around.
+func intervalsFitOffset[T int64 | uint64](intervals []interval[T], offset uint64) T {
+	span := intervalsSpan(intervals)
+	if span == 0 {
+		// A single interval covering every value
+		return T(uint64(intervals[0].lo) + offset)
+	}
+
+	offset %= span
+	for _, i := range intervals {
+		width := uint64(i.hi) - uint64(i.lo) + 1
+		if offset < width {
+			return T(uint64(i.lo) + offset)
+		}
+		offset -= width
+	}
+	panic("unreachable: offset is less than the span of the intervals")
+}
+
+// Returns the offset of val within the values of the intervals, the inverse
+// of intervalsFitOffset. Returns false if val isn't in any interval.
+func intervalsOffset[T int64 | uint64](intervals []interval[T], val T) (uint64, bool) {
+	offset := uint64(0)
+	for _, i := range intervals {
+		if val >= i.lo && val <= i.hi {
+			return offset + uint64(val) - uint64(i.lo), true
+		}
+		offset += uint64(i.hi) - uint64(i.lo) + 1
+	}
+	return 0, false
+}
+
+// Returns true if val is in one of the intervals
+func intervalsContain[T int64 | uint64 | float64](intervals []interval[T], val T) bool {
+	return slices.ContainsFunc(intervals, func(i interval[T]) bool {
+		return val >= i.lo && val <= i.hi
+	})
+}
+
+// Describes the intervals, e.g. "0 to 10, 100 to 200"
+func intervalsString[T int64 | uint64 | float64](intervals []interval[T]) string {
+	parts := []string{}
+	for _, i := range intervals {
+		parts = append(parts, fmt.Sprintf("%v to %v", i.lo, i.hi))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -1,7 +1,7 @@
 package fuzzhelper
 
 type fillSliceStruct[T any] struct {
-	allowableTypes []T `fuzz-ignore`
+	allowableTypes []T
 	Result         []T `fuzz-interface-method:"InterfaceOptions"`
 }
 
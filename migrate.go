@@ -0,0 +1,274 @@
+package fuzzhelper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const corpusHeader = "go test fuzz v1"
+
+// MigrateCorpus rewrites every file in a fuzz corpus directory, such as
+// testdata/fuzz/FuzzTarget, so that each input fills to the same value after
+// the layout of a fuzzed type has changed.
+//
+// from and to are pointers to the old and new versions of the type. Each
+// input is filled into the old type, the values are copied field by field,
+// by name, onto the new type and the result is re-encoded with Encode.
+// Fields which only exist in the new type are left as zero values. The
+// old version of the type needs to be kept, under a different name, while
+// the migration is run, e.g. from a test or a small program.
+//
+// Only corpus files with a single []byte argument are supported. Every file
+// which can be migrated is rewritten, files which can't be migrated are left
+// unchanged and reported, one per line, in the returned error.
+func MigrateCorpus(dir string, from, to any, opts ...Option) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return err
+	}
+
+	failures := []error{}
+	for _, path := range paths {
+		if err := migrateCorpusFile(path, from, to, opts); err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+
+	return errors.Join(failures...)
+}
+
+func migrateCorpusFile(path string, from, to any, opts []Option) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	input, err := parseCorpusFile(data)
+	if err != nil {
+		return err
+	}
+
+	output, err := MigrateInput(input, from, to, opts...)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, formatCorpusFile(output), 0o644)
+}
+
+// MigrateInput converts a single input for the type of from into an input
+// for the type of to. See MigrateCorpus.
+//
+// The input is filled with the same opts as the output is encoded with.
+// Under StopOnExhaustion, the default, the values Fill doesn't reach once
+// the input runs out are left as zero values. A zero value which doesn't
+// fit its tags, e.g. a fuzz-int-range:"1,4", can't be encoded and an error
+// is returned. Passing WithExhaustion(MinimumOnExhaustion) fills these
+// values with their minimum instead, which is always encoded.
+func MigrateInput(input []byte, from, to any, opts ...Option) ([]byte, error) {
+	fromType := reflect.TypeOf(from)
+	toType := reflect.TypeOf(to)
+	if fromType.Kind() != reflect.Pointer || toType.Kind() != reflect.Pointer {
+		return nil, fmt.Errorf("can only migrate between pointer types, found %s and %s", fromType, toType)
+	}
+
+	config := newFillConfig(opts)
+	if config.result == nil {
+		config.result = &FillResult{}
+	}
+	fromVal := reflect.New(fromType.Elem())
+	fill(fromVal.Interface(), newByteConsumer(input), config)
+
+	toVal := reflect.New(toType.Elem())
+	copyPointee(toVal, fromVal)
+
+	output, err := Encode(toVal.Interface(), opts...)
+	if err != nil && config.exhaustion == StopOnExhaustion && config.result.Truncated {
+		return nil, fmt.Errorf("input runs out at %s, the zero values left can't be encoded, use WithExhaustion(MinimumOnExhaustion) to fill them with their minimum: %w", config.result.TruncatedAt, err)
+	}
+	return output, err
+}
+
+func parseCorpusFile(data []byte) ([]byte, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || strings.TrimSpace(lines[0]) != corpusHeader {
+		return nil, fmt.Errorf("expected %q followed by a single value", corpusHeader)
+	}
+
+	value := strings.TrimSpace(lines[1])
+	if !strings.HasPrefix(value, "[]byte(") || !strings.HasSuffix(value, ")") {
+		return nil, fmt.Errorf("expected a single []byte value, found %s", value)
+	}
+
+	unquoted, err := strconv.Unquote(value[len("[]byte(") : len(value)-1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed []byte value %s: %w", value, err)
+	}
+
+	return []byte(unquoted), nil
+}
+
+func formatCorpusFile(input []byte) []byte {
+	b := bytes.Buffer{}
+	fmt.Fprintf(&b, "%s\n[]byte(%q)\n", corpusHeader, input)
+	return b.Bytes()
+}
+
+// Copies the value pointed to by src into the value pointed to by dst,
+// matching struct fields by name. Values which can't be converted to the
+// type of dst are left as zero values. Values shared by pointers in src,
+// including cycles, are also shared in dst.
+func copyPointee(dst, src reflect.Value) {
+	c := newValueCopier(true)
+	c.copied[newPointerKey(src, dst.Type())] = dst
+	c.copyValue(dst.Elem(), src.Elem())
+}
+
+// Returns a copy of the pointer src which shares no values with src, or with
+// any other value. Cycles in src are still cycles in the copy.
+func unsharedCopy(src reflect.Value) reflect.Value {
+	return newValueCopier(false).copyPointer(src.Type(), src)
+}
+
+// Identifies a pointer in the source value, copied to a pointer type
+type pointerKey struct {
+	from reflect.Type
+	to   reflect.Type
+	addr uintptr
+}
+
+func newPointerKey(src reflect.Value, to reflect.Type) pointerKey {
+	return pointerKey{
+		from: src.Type(),
+		to:   to,
+		addr: src.Pointer(),
+	}
+}
+
+type valueCopier struct {
+	// The copies of the pointers currently being copied, and when shared
+	// is true the copies of every pointer already copied
+	copied map[pointerKey]reflect.Value
+	shared bool
+}
+
+func newValueCopier(shared bool) valueCopier {
+	return valueCopier{
+		copied: map[pointerKey]reflect.Value{},
+		shared: shared,
+	}
+}
+
+// Returns a copy, of type typ, of the non-nil pointer src
+func (c valueCopier) copyPointer(typ reflect.Type, src reflect.Value) reflect.Value {
+	key := newPointerKey(src, typ)
+	if copied, ok := c.copied[key]; ok {
+		return copied
+	}
+
+	copied := reflect.New(typ.Elem())
+	c.copied[key] = copied
+	c.copyValue(copied.Elem(), src.Elem())
+	if !c.shared {
+		delete(c.copied, key)
+	}
+	return copied
+}
+
+func (c valueCopier) copyValue(dst, src reflect.Value) {
+	if !dst.CanSet() || !src.IsValid() {
+		return
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		if src.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < dst.NumField(); i++ {
+			name := dst.Type().Field(i).Name
+			c.copyValue(dst.Field(i), src.FieldByName(name))
+		}
+
+	case reflect.Pointer:
+		if src.Kind() != reflect.Pointer || src.IsNil() {
+			return
+		}
+		dst.Set(c.copyPointer(dst.Type(), src))
+
+	case reflect.Slice:
+		if (src.Kind() != reflect.Slice && src.Kind() != reflect.Array) || (src.Kind() == reflect.Slice && src.IsNil()) {
+			return
+		}
+		dst.Set(reflect.MakeSlice(dst.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			c.copyValue(dst.Index(i), src.Index(i))
+		}
+
+	case reflect.Array:
+		if src.Kind() != reflect.Slice && src.Kind() != reflect.Array {
+			return
+		}
+		for i := 0; i < min(dst.Len(), src.Len()); i++ {
+			c.copyValue(dst.Index(i), src.Index(i))
+		}
+
+	case reflect.Map:
+		if src.Kind() != reflect.Map || src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), src.Len()))
+		iter := src.MapRange()
+		for iter.Next() {
+			key, val := newMapEntry(dst)
+			c.copyValue(key, iter.Key())
+			c.copyValue(val, iter.Value())
+			dst.SetMapIndex(key, val)
+		}
+
+	case reflect.Interface:
+		if src.Kind() != reflect.Interface || src.IsNil() {
+			return
+		}
+		elem := src.Elem()
+		if !elem.Type().AssignableTo(dst.Type()) {
+			return
+		}
+		if elem.Kind() == reflect.Pointer && !elem.IsNil() {
+			// Copy the value pointed to, so the copy doesn't share
+			// any data with src
+			elem = c.copyPointer(elem.Type(), elem)
+		}
+		dst.Set(elem)
+
+	default:
+		class := kindClass(dst.Kind())
+		if class == "" || class != kindClass(src.Kind()) || !src.Type().ConvertibleTo(dst.Type()) {
+			return
+		}
+		dst.Set(src.Convert(dst.Type()))
+	}
+}
+
+// Groups kinds which can be sensibly converted between each other
+func kindClass(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	default:
+		// Other kinds (e.g. channels and functions) are not copied
+		return ""
+	}
+}
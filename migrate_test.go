@@ -0,0 +1,214 @@
+package fuzzhelper
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type migrateStepV1 struct {
+	Value string `fuzz-string-range:"0,10"`
+	Count int16
+}
+
+type migrateStepV2 struct {
+	Count int16
+	// New field, inserted before an existing one
+	Flag  bool
+	Value string `fuzz-string-range:"0,10"`
+}
+
+type migrateStepsV1 struct {
+	Steps []migrateStepV1 `fuzz-slice-range:"0,5"`
+}
+
+type migrateStepsV2 struct {
+	Steps []migrateStepV2 `fuzz-slice-range:"0,5"`
+}
+
+func TestMigrateInput(t *testing.T) {
+	v1 := migrateStepsV1{
+		Steps: []migrateStepV1{
+			{Value: "abc", Count: 1},
+			{Value: "", Count: -2},
+		},
+	}
+	input, err := Encode(&v1)
+	require.NoError(t, err)
+
+	output, err := MigrateInput(input, &migrateStepsV1{}, &migrateStepsV2{})
+	require.NoError(t, err)
+
+	v2 := migrateStepsV2{}
+	Fill(&v2, output)
+	assert.Equal(t, migrateStepsV2{
+		Steps: []migrateStepV2{
+			{Value: "abc", Count: 1},
+			{Value: "", Count: -2},
+		},
+	}, v2)
+}
+
+func TestMigrateCorpus(t *testing.T) {
+	dir := t.TempDir()
+
+	v1 := migrateStepsV1{
+		Steps: []migrateStepV1{
+			{Value: "hello", Count: 300},
+		},
+	}
+	input, err := Encode(&v1)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "0123456789abcdef")
+	require.NoError(t, os.WriteFile(path, formatCorpusFile(input), 0o644))
+
+	require.NoError(t, MigrateCorpus(dir, &migrateStepsV1{}, &migrateStepsV2{}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	output, err := parseCorpusFile(data)
+	require.NoError(t, err)
+
+	v2 := migrateStepsV2{}
+	Fill(&v2, output)
+	assert.Equal(t, migrateStepsV2{
+		Steps: []migrateStepV2{
+			{Value: "hello", Count: 300},
+		},
+	}, v2)
+}
+
+func TestMigrateCorpus_BadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	input, err := Encode(&migrateStepsV1{Steps: []migrateStepV1{{Value: "hello", Count: 300}}})
+	require.NoError(t, err)
+	goodPath := filepath.Join(dir, "good")
+	require.NoError(t, os.WriteFile(goodPath, formatCorpusFile(input), 0o644))
+
+	badPath := filepath.Join(dir, "bad")
+	badData := []byte("go test fuzz v1\nint(1)\n")
+	require.NoError(t, os.WriteFile(badPath, badData, 0o644))
+
+	// The bad file is reported, and the good file is still migrated
+	err = MigrateCorpus(dir, &migrateStepsV1{}, &migrateStepsV2{})
+	assert.EqualError(t, err, badPath+": expected a single []byte value, found int(1)")
+
+	data, err := os.ReadFile(badPath)
+	require.NoError(t, err)
+	assert.Equal(t, badData, data)
+
+	data, err = os.ReadFile(goodPath)
+	require.NoError(t, err)
+	output, err := parseCorpusFile(data)
+	require.NoError(t, err)
+	v2 := migrateStepsV2{}
+	Fill(&v2, output)
+	assert.Equal(t, []migrateStepV2{{Value: "hello", Count: 300}}, v2.Steps)
+}
+
+func TestParseCorpusFile(t *testing.T) {
+	input, err := parseCorpusFile([]byte("go test fuzz v1\n[]byte(\"\\x01\\x02abc\")\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 'a', 'b', 'c'}, input)
+
+	// Round trip through formatCorpusFile
+	input, err = parseCorpusFile(formatCorpusFile([]byte{0, 255, '"'}))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 255, '"'}, input)
+
+	_, err = parseCorpusFile([]byte("go test fuzz v1\nint(1)\n"))
+	assert.EqualError(t, err, "expected a single []byte value, found int(1)")
+
+	_, err = parseCorpusFile([]byte("go test fuzz v1\n[]byte(\"a\")\nint(1)\n"))
+	assert.EqualError(t, err, `expected "go test fuzz v1" followed by a single value`)
+}
+
+type migrateOpStepV1 struct {
+	Op    int    `fuzz-int-range:"1,4"`
+	Name  string `fuzz-string-range:"1,5"`
+	Inner *migrateOpArg
+}
+
+type migrateOpStepV2 struct {
+	Op    int `fuzz-int-range:"1,4"`
+	Flag  bool
+	Name  string `fuzz-string-range:"1,5"`
+	Inner *migrateOpArg
+}
+
+type migrateOpArg struct {
+	Arg uint8 `fuzz-uint-range:"10,20"`
+}
+
+type migrateOpsV1 struct {
+	Steps []migrateOpStepV1 `fuzz-slice-range:"1,8"`
+}
+
+type migrateOpsV2 struct {
+	Steps []migrateOpStepV2 `fuzz-slice-range:"1,8"`
+}
+
+// Inputs which run out of bytes part way through a value migrate to inputs
+// which fill the same zero values, if the zero values can be encoded
+func TestMigrateInput_RunsOut(t *testing.T) {
+	v1 := migrateStepsV1{
+		Steps: []migrateStepV1{
+			{Value: "abc", Count: 300},
+		},
+	}
+	input, err := Encode(&v1)
+	require.NoError(t, err)
+	// Cut off the Count
+	input = input[:len(input)-2]
+
+	output, err := MigrateInput(input, &migrateStepsV1{}, &migrateStepsV2{})
+	require.NoError(t, err)
+
+	v2 := migrateStepsV2{}
+	Fill(&v2, output)
+	assert.Equal(t, []migrateStepV2{{Value: "abc"}}, v2.Steps)
+
+	// A zero Op is outside of its range, so the input can't be migrated
+	// unless the values left take their minimum
+	input = []byte{1}
+	_, err = MigrateInput(input, &migrateOpsV1{}, &migrateOpsV2{})
+	assert.EqualError(t, err, "input runs out at *(migrateOpsV1).Steps[0] (migrateOpStepV1), the zero values left can't be encoded, use WithExhaustion(MinimumOnExhaustion) to fill them with their minimum: cannot encode *(migrateOpsV2).Steps[0](migrateOpStepV2).Op (int): value 0 is outside of range 1 to 4")
+
+	output, err = MigrateInput(input, &migrateOpsV1{}, &migrateOpsV2{}, WithExhaustion(MinimumOnExhaustion))
+	require.NoError(t, err)
+
+	ops := migrateOpsV2{}
+	Fill(&ops, output, WithExhaustion(MinimumOnExhaustion))
+	assert.Equal(t, []migrateOpStepV2{{Op: 1, Name: "\x00"}, {Op: 1, Name: "\x00"}}, ops.Steps)
+}
+
+// Inputs which run out of bytes part way through a value still migrate when
+// the values left take their minimum
+func TestMigrateInput_Random(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 1000 {
+		input := make([]byte, r.IntN(200))
+		for i := range input {
+			input[i] = byte(r.Uint32())
+		}
+
+		output, err := MigrateInput(input, &migrateOpsV1{}, &migrateOpsV2{}, WithExhaustion(MinimumOnExhaustion))
+		require.NoError(t, err, "%v", input)
+
+		v1 := migrateOpsV1{}
+		Fill(&v1, input, WithExhaustion(MinimumOnExhaustion))
+		v2 := migrateOpsV2{}
+		Fill(&v2, output, WithExhaustion(MinimumOnExhaustion))
+		require.Len(t, v2.Steps, len(v1.Steps))
+		for i, step := range v1.Steps {
+			assert.Equal(t, step.Op, v2.Steps[i].Op)
+			assert.Equal(t, step.Name, v2.Steps[i].Name)
+		}
+	}
+}
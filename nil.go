@@ -0,0 +1,70 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// WithNilPercent makes every pointer, and every interface with a
+// fuzz-interface-method tag, nil percent% of the time. Individual fields
+// can be given their own percentage with a fuzz-nil:"25" tag, which takes
+// precedence over this option. The default is 0, pointers and interfaces
+// are only left nil when Fill runs out of bytes.
+//
+// A nil-able value consumes a control byte, before any bytes used to fill
+// the value it points to. The byte is scaled from 0-255 down to 0-99, and the
+// value is nil if the scaled byte is less than percent.
+func WithNilPercent(percent int) Option {
+	return func(config *fillConfig) {
+		config.nilPercent = min(max(percent, 0), 100)
+	}
+}
+
+type nilTag struct {
+	wasSet  bool
+	percent int
+}
+
+func newNilTag(structVal reflect.Value, field reflect.StructField, tag string) nilTag {
+	valStr, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return nilTag{}
+	}
+
+	percent, err := strconv.Atoi(valStr)
+	if err != nil || percent < 0 || percent > 100 {
+		panic(fmt.Errorf("%s.%s has invalid %s %q, must be a percentage from 0 to 100", structVal.Type(), field.Name, tag, valStr))
+	}
+
+	return nilTag{
+		wasSet:  true,
+		percent: percent,
+	}
+}
+
+// Returns the percentage of the time a pointer or interface with tags should
+// be nil
+func (config fillConfig) nilChance(tags fuzzTags) int {
+	if tags.nilPercent.wasSet {
+		return tags.nilPercent.percent
+	}
+	return config.nilPercent
+}
+
+// Consumes a control byte, returns true if the value should be nil
+func (c *byteConsumer) consumeNil(percent int) bool {
+	b := c.consumeControl(1)[0]
+	// Scaling, rather than taking the byte modulo 100, picks each
+	// percentage from 2 or 3 bytes, so nils aren't favoured
+	return int(b)*100/256 < percent
+}
+
+// Used by Encode and tests
+func (c *byteConsumer) pushNil(isNil bool) {
+	if isNil {
+		c.pushControl([]byte{0})
+	} else {
+		c.pushControl([]byte{255})
+	}
+}
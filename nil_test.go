@@ -0,0 +1,153 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nilStruct struct {
+	PointerField   *int8         `fuzz-nil:"25"`
+	InterfaceField interfaceDemo `fuzz-interface-method:"InterfaceOptions" fuzz-nil:"50"`
+	NeverNilField  *int8         `fuzz-nil:"0"`
+	PlainField     *int8
+}
+
+func (s *nilStruct) InterfaceOptions() []interfaceDemo {
+	return []interfaceDemo{&interfaceDemoA{}, &interfaceDemoB{}}
+}
+
+func TestFill_NilTag(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// PointerField, 60 scales to 23 < 25 so the pointer is nil
+	c.pushBytes([]byte{60})
+	// InterfaceField, 150 scales to 58 >= 50 so the interface is set
+	c.pushBytes([]byte{150})
+	c.pushUint64(1, bytesForNative)
+	// NeverNilField and PlainField consume no control byte, the values
+	// they point to are filled after the fields
+	c.pushInt64(3, bytesFor8)
+	c.pushInt64(4, bytesFor8)
+
+	val := nilStruct{}
+	Fill(&val, c.getRawBytes())
+
+	three, four := int8(3), int8(4)
+	assert.Equal(t, nilStruct{
+		PointerField:   nil,
+		InterfaceField: &interfaceDemoB{},
+		NeverNilField:  &three,
+		PlainField:     &four,
+	}, val)
+}
+
+func TestFill_NilPercentOption(t *testing.T) {
+	nils := 0
+	for i := 0; i < 256; i++ {
+		c := newByteConsumer([]byte{})
+		// PointerField is always nil
+		c.pushBytes([]byte{0})
+		// InterfaceField is never nil
+		c.pushBytes([]byte{255})
+		c.pushUint64(0, bytesForNative)
+		// PlainField is nil 10% of the time, for the bytes 0 to 25
+		c.pushBytes([]byte{byte(i)})
+		// The pointed to values are filled after the fields
+		c.pushInt64(1, bytesFor8)
+		c.pushInt64(1, bytesFor8)
+
+		val := nilStruct{}
+		Fill(&val, c.getRawBytes(), WithNilPercent(10))
+		require.Nil(t, val.PointerField)
+		require.NotNil(t, val.InterfaceField)
+		require.NotNil(t, val.NeverNilField)
+		if val.PlainField == nil {
+			nils++
+		}
+	}
+
+	assert.Equal(t, 26, nils)
+}
+
+func TestEncode_Nil(t *testing.T) {
+	one := int8(1)
+	for _, val := range []nilStruct{
+		{},
+		{PointerField: &one, NeverNilField: &one},
+		{InterfaceField: &interfaceDemoA{IntField: 7}, NeverNilField: &one, PlainField: &one},
+	} {
+		encoded, err := Encode(&val, WithNilPercent(50))
+		require.NoError(t, err)
+
+		filled := nilStruct{}
+		Fill(&filled, encoded, WithNilPercent(50))
+
+		expected := val
+		if expected.NeverNilField == nil {
+			// Fill always allocates this pointer
+			expected.NeverNilField = new(int8)
+		}
+		assert.Equal(t, expected, filled)
+	}
+}
+
+func TestEncode_AlwaysNil(t *testing.T) {
+	type alwaysNilStruct struct {
+		PointerField *int `fuzz-nil:"100"`
+	}
+
+	_, err := Encode(&alwaysNilStruct{PointerField: new(int)})
+	assert.EqualError(t, err, "cannot encode *(alwaysNilStruct).PointerField (*int): it is not nil, but is always nil")
+}
+
+func TestFingerprint_NilPercent(t *testing.T) {
+	assert.NotEqual(t, Fingerprint(&nilStruct{}), Fingerprint(&nilStruct{}, WithNilPercent(10)))
+}
+
+type badNilTag struct {
+	PointerField *int `fuzz-nil:"101"`
+}
+
+func TestFill_BadNilTag(t *testing.T) {
+	assert.PanicsWithError(t, `fuzzhelper.badNilTag.PointerField has invalid fuzz-nil "101", must be a percentage from 0 to 100`, func() {
+		Fill(&badNilTag{}, []byte{1})
+	})
+}
+
+func ExampleDescribe_nil() {
+	Describe(&nilStruct{}, WithNilPercent(10))
+	// Output:*(nilStruct).PointerField (*int8)
+	//	nil: 25%
+	//*(nilStruct).InterfaceField (interface)
+	//	method (InterfaceOptions): [*fuzzhelper.inte...
+	//	nil: 50%
+	//*(nilStruct).PlainField (*int8)
+	//	nil: 10%
+	//*(nilStruct).PointerField (*int8)
+	//	range: 0 to 0
+	//*(nilStruct).NeverNilField (*int8)
+	//	range: 0 to 0
+	//*(nilStruct).PlainField (*int8)
+	//	range: 0 to 0
+}
+
+// Each percentage is made nil by its share of the 256 byte values
+func TestConsumeNil_Unbiased(t *testing.T) {
+	for percent := 0; percent <= 100; percent++ {
+		nils := 0
+		for b := 0; b < 256; b++ {
+			if newByteConsumer([]byte{byte(b)}).consumeNil(percent) {
+				nils++
+			}
+		}
+		assert.InDelta(t, float64(percent)*256/100, float64(nils), 1, "percent %d", percent)
+	}
+
+	c := newByteConsumer([]byte{})
+	c.pushNil(true)
+	c.pushNil(false)
+	filled := newByteConsumer(c.getRawBytes())
+	assert.True(t, filled.consumeNil(1))
+	assert.False(t, filled.consumeNil(99))
+}
@@ -0,0 +1,35 @@
+package fuzzhelper
+
+// An Option configures how Fill consumes bytes and builds values.
+type Option func(*fillConfig)
+
+type fillConfig struct {
+	exhaustion      ExhaustionPolicy
+	expansionLimit  int
+	result          *FillResult
+	format          ByteFormat
+	packBits        bool
+	layout          ByteLayout
+	edgeBias        bool
+	nilPercent      int
+	maxDepth        int
+	aliasing        bool
+	traversal       TraversalOrder
+	distinctMapKeys bool
+	byteChunks      bool
+}
+
+func newFillConfig(opts []Option) fillConfig {
+	config := fillConfig{
+		exhaustion:     StopOnExhaustion,
+		expansionLimit: defaultExpansionLimit,
+		format:         NativeFormat,
+		layout:         InterleavedLayout,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return config
+}
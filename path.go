@@ -28,6 +28,16 @@ func (p valuePath) containsType(typ reflect.Type) bool {
 	return false
 }
 
+// Returns true if the pointer ptr is one of the values in the path
+func (p valuePath) containsPointer(ptr reflect.Value) bool {
+	for _, val := range p.values {
+		if val.Kind() == reflect.Pointer && val.Type() == ptr.Type() && val.Pointer() == ptr.Pointer() {
+			return true
+		}
+	}
+	return false
+}
+
 var pointerRegex = regexp.MustCompile(`\.(\**)\(`)
 
 func (p valuePath) pathString(value reflect.Value) string {
@@ -0,0 +1,327 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// fuzz-regex:"^[a-z]{1,8}@[a-z]+\.(com|org)$" makes strings which match a
+// regular expression, using the syntax of the regexp package. The string is
+// made by walking the parsed expression, choosing an alternative for each
+// |, a number of repeats for each repetition and a rune for each character
+// class or '.'. Alternatives and repeats are chosen like method options,
+// runes like the runes of a fuzz-charset.
+//
+// Repetitions without a maximum, such as * and +, repeat at most
+// maxRegexRepeat times more than their minimum. Assertions such as ^, $
+// and \b are ignored, and letters matched without case are made as written.
+// fuzz-string-range has no effect on a string with a regex.
+
+// The most repeats, beyond the minimum, made for a repetition without a
+// maximum
+const maxRegexRepeat = 10
+
+type regexTag struct {
+	wasSet  bool
+	pattern string
+	re      *syntax.Regexp
+	// Matches every string which can be made from re, see matchAll
+	match *regexp.Regexp
+}
+
+func newRegexTag(structVal reflect.Value, field reflect.StructField, prefix string, t fuzzTags) regexTag {
+	pattern, ok := field.Tag.Lookup(prefix + "regex")
+	if !ok {
+		return regexTag{}
+	}
+
+	if !containsString(field.Type) {
+		panic(fmt.Errorf("%s.%s has %sregex, but %s does not contain a string", structVal.Type(), field.Name, prefix, field.Type))
+	}
+
+	if t.charset.wasSet || t.stringValues.wasSet {
+		panic(fmt.Errorf("%s.%s has %sregex, which can't be used with %scharset, %sstring-exact, %sstring-raw or %sstring-method", structVal.Type(), field.Name, prefix, prefix, prefix, prefix, prefix))
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		panic(fmt.Errorf("%s.%s has invalid %sregex %q, %w", structVal.Type(), field.Name, prefix, pattern, err))
+	}
+
+	return regexTag{
+		wasSet:  true,
+		pattern: pattern,
+		re:      re,
+		match:   regexp.MustCompile(`^(?:` + matchAll(re).String() + `)$`),
+	}
+}
+
+// Returns a copy of re with its assertions, which are ignored when making
+// strings, replaced by empty matches. The copy matches every string which
+// can be made from re, and perhaps more, as its repetitions have no limit.
+func matchAll(re *syntax.Regexp) *syntax.Regexp {
+	switch re.Op {
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return &syntax.Regexp{Op: syntax.OpEmptyMatch}
+	}
+	all := *re
+	all.Sub = make([]*syntax.Regexp, len(re.Sub))
+	for i, sub := range re.Sub {
+		all.Sub[i] = matchAll(sub)
+	}
+	return &all
+}
+
+// Returns the runes matched by re, a character class or '.'
+func regexClass(re *syntax.Regexp) []interval[int64] {
+	switch re.Op {
+	case syntax.OpAnyChar:
+		return namedCharsets["unicode"]
+	case syntax.OpAnyCharNotNL:
+		return classRunes([]rune{0, '\n' - 1, '\n' + 1, utf8.MaxRune})
+	default:
+		return classRunes(re.Rune)
+	}
+}
+
+// Returns the fewest and most repeats made for re, a repetition
+func regexRepeats(re *syntax.Regexp) (lo, hi int) {
+	switch re.Op {
+	case syntax.OpStar:
+		return 0, maxRegexRepeat
+	case syntax.OpPlus:
+		return 1, 1 + maxRegexRepeat
+	case syntax.OpQuest:
+		return 0, 1
+	default:
+		if re.Max < 0 {
+			return re.Min, re.Min + maxRegexRepeat
+		}
+		return re.Min, re.Max
+	}
+}
+
+// Returns a string matching re
+func (c *byteConsumer) regexString(re *syntax.Regexp, format ByteFormat) string {
+	b := &strings.Builder{}
+	c.writeRegex(b, re, format)
+	return b.String()
+}
+
+func (c *byteConsumer) writeRegex(b *strings.Builder, re *syntax.Regexp, format ByteFormat) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			b.WriteRune(r)
+		}
+
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		set := regexClass(re)
+		if len(set) == 0 {
+			// An empty class matches nothing
+			return
+		}
+		b.WriteRune(rune(intervalsFitOffset(set, c.consumeUint64(runeSize(set)))))
+
+	case syntax.OpCapture:
+		c.writeRegex(b, re.Sub[0], format)
+
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			c.writeRegex(b, sub, format)
+		}
+
+	case syntax.OpAlternate:
+		c.writeRegex(b, re.Sub[c.regexChoice(len(re.Sub), format)], format)
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		lo, hi := regexRepeats(re)
+		for range lo + c.regexChoice(hi-lo+1, format) {
+			c.writeRegex(b, re.Sub[0], format)
+		}
+	}
+	// Empty matches and assertions make no runes
+}
+
+// Chooses between n options, a single option consumes no bytes
+func (c *byteConsumer) regexChoice(n int, format ByteFormat) int {
+	if n == 1 {
+		return 0
+	}
+	return c.consumeChoice(n, format.choiceSize(n))
+}
+
+// A choice, or rune, picked by regexString
+type regexPick struct {
+	choice bool
+	index  int
+	n      int
+	offset uint64
+	size   uintptr
+}
+
+// Returns the picks regexString makes to create str. Returns false if str
+// can't be made from t's regex.
+func regexPicks(t regexTag, str string) ([]regexPick, bool) {
+	// Strings which don't match are rejected in linear time, before
+	// searching for the picks
+	if !t.match.MatchString(str) {
+		return nil, false
+	}
+
+	m := regexMatcher{
+		runes: []rune(str),
+		memo:  map[regexMatchKey][]regexEnd{},
+	}
+	for _, end := range m.ends(t.re, 0) {
+		if end.pos == len(m.runes) {
+			return end.picks, true
+		}
+	}
+	return nil, false
+}
+
+// Finds the ways a regex matches runes. The ends of each sub-expression at
+// each position are memoized, so the search takes polynomial time even for
+// regexes such as (a*)*b.
+type regexMatcher struct {
+	runes []rune
+	memo  map[regexMatchKey][]regexEnd
+}
+
+type regexMatchKey struct {
+	re  *syntax.Regexp
+	pos int
+}
+
+// A position a match ends at, and the picks which make the match
+type regexEnd struct {
+	pos   int
+	picks []regexPick
+}
+
+// Returns the positions at which re, matched from pos, can end. Each
+// position appears once, with the picks of the first way found to reach it.
+// Alternatives are tried in order and repetitions with the most repeats
+// first.
+func (m *regexMatcher) ends(re *syntax.Regexp, pos int) []regexEnd {
+	key := regexMatchKey{re: re, pos: pos}
+	if ends, ok := m.memo[key]; ok {
+		return ends
+	}
+	ends := m.matchEnds(re, pos)
+	m.memo[key] = ends
+	return ends
+}
+
+func (m *regexMatcher) matchEnds(re *syntax.Regexp, pos int) []regexEnd {
+	runes := m.runes
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return nil
+
+	case syntax.OpLiteral:
+		end := pos + len(re.Rune)
+		if end > len(runes) || !slices.Equal(runes[pos:end], re.Rune) {
+			return nil
+		}
+		return []regexEnd{{pos: end}}
+
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		if pos >= len(runes) {
+			return nil
+		}
+		set := regexClass(re)
+		offset, ok := intervalsOffset(set, int64(runes[pos]))
+		if !ok {
+			return nil
+		}
+		return []regexEnd{{pos: pos + 1, picks: []regexPick{{offset: offset, size: runeSize(set)}}}}
+
+	case syntax.OpCapture:
+		return m.ends(re.Sub[0], pos)
+
+	case syntax.OpConcat:
+		ends := []regexEnd{{pos: pos}}
+		for _, sub := range re.Sub {
+			ends = m.follow(ends, sub)
+		}
+		return ends
+
+	case syntax.OpAlternate:
+		ends := []regexEnd{}
+		for i, sub := range re.Sub {
+			for _, end := range m.ends(sub, pos) {
+				ends = addRegexEnd(ends, end.pos, choicePicks(i, len(re.Sub)), end.picks)
+			}
+		}
+		return ends
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		lo, hi := regexRepeats(re)
+		// The ends after each number of repeats
+		repeats := [][]regexEnd{{{pos: pos}}}
+		for count := 1; count <= hi; count++ {
+			repeats = append(repeats, m.follow(repeats[count-1], re.Sub[0]))
+		}
+		ends := []regexEnd{}
+		for count := hi; count >= lo; count-- {
+			for _, end := range repeats[count] {
+				ends = addRegexEnd(ends, end.pos, choicePicks(count-lo, hi-lo+1), end.picks)
+			}
+		}
+		return ends
+
+	default:
+		// Empty matches and assertions match no runes
+		return []regexEnd{{pos: pos}}
+	}
+}
+
+// Returns the ends of matching re after each of ends
+func (m *regexMatcher) follow(ends []regexEnd, re *syntax.Regexp) []regexEnd {
+	next := []regexEnd{}
+	for _, end := range ends {
+		for _, subEnd := range m.ends(re, end.pos) {
+			next = addRegexEnd(next, subEnd.pos, end.picks, subEnd.picks)
+		}
+	}
+	return next
+}
+
+// Adds an end at pos, made by the picks in first followed by those in rest,
+// unless ends already has an end at pos
+func addRegexEnd(ends []regexEnd, pos int, first, rest []regexPick) []regexEnd {
+	for _, end := range ends {
+		if end.pos == pos {
+			return ends
+		}
+	}
+	// Clipping forces a copy, so picks can be shared between ends
+	return append(ends, regexEnd{pos: pos, picks: append(slices.Clip(first), rest...)})
+}
+
+// Returns the pick of index from n options, a single option is not a pick,
+// see regexChoice
+func choicePicks(index, n int) []regexPick {
+	if n == 1 {
+		return nil
+	}
+	return []regexPick{{choice: true, index: index, n: n}}
+}
+
+// Pushes the picks made by regexString, see regexPicks
+func (c *byteConsumer) pushRegexPicks(picks []regexPick, format ByteFormat) {
+	for _, pick := range picks {
+		if pick.choice {
+			c.pushChoice(pick.index, pick.n, format.choiceSize(pick.n))
+		} else {
+			c.pushUint64(pick.offset, pick.size)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package fuzzhelper
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type regexStruct struct {
+	EmailField   string   `fuzz-regex:"^[a-z]{1,8}@[a-z]+\\.(com|org)$"`
+	VersionField string   `fuzz-regex:"v[0-9]\\.[0-9]"`
+	PathsField   []string `fuzz-slice-range:"2,2" fuzz-regex:"(/[a-c;]+)+"`
+}
+
+func TestFill_Regex(t *testing.T) {
+	c := newByteConsumer([]byte{})
+	// EmailField, 3 runes before the @
+	c.pushUint64(2, bytesForNative)
+	c.pushBytes([]byte{'b' - 'a', 'o' - 'a', 'b' - 'a'})
+	// 2 runes after the @, a repeat of + is counted from 1
+	c.pushUint64(1, bytesForNative)
+	c.pushBytes([]byte{'i' - 'a', 'o' - 'a'})
+	c.pushUint64(1, bytesForNative)
+	// VersionField, has no choices
+	c.pushBytes([]byte{1, 2})
+	// PathsField, the ";" in the regex is not a tag level
+	c.pushUint64(0, bytesForNative)
+	c.pushUint64(0, bytesForNative)
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{3})
+	c.pushUint64(1, bytesForNative)
+	c.pushUint64(0, bytesForNative)
+	c.pushBytes([]byte{0})
+	c.pushUint64(1, bytesForNative)
+	c.pushBytes([]byte{1, 2})
+
+	val := regexStruct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.Equal(t, regexStruct{
+		EmailField:   "bob@io.org",
+		VersionField: "v1.2",
+		PathsField:   []string{"/c", "/;/ab"},
+	}, val)
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	assert.Equal(t, c.getRawBytes(), encoded)
+}
+
+func TestFill_RegexMatches(t *testing.T) {
+	email := regexp.MustCompile("^[a-z]{1,8}@[a-z]+\\.(com|org)$")
+	version := regexp.MustCompile("v[0-9]\\.[0-9]")
+	path := regexp.MustCompile("(/[a-c;]+)+")
+
+	for _, bytes := range [][]byte{
+		{},
+		{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		{255, 254, 253, 252, 251, 250, 249, 248, 247, 246, 245, 244, 243, 242, 241, 240},
+	} {
+		val := regexStruct{}
+		Fill(&val, bytes, WithExhaustion(PRNGOnExhaustion))
+		assert.Regexp(t, email, val.EmailField)
+		assert.Regexp(t, version, val.VersionField)
+		for _, p := range val.PathsField {
+			assert.Regexp(t, path, p)
+		}
+
+		encoded, err := Encode(&val)
+		require.NoError(t, err)
+		filled := regexStruct{}
+		Fill(&filled, encoded)
+		assert.Equal(t, val, filled)
+	}
+}
+
+func TestEncode_Regex(t *testing.T) {
+	_, err := Encode(&regexStruct{EmailField: "bob@io.net"})
+	assert.EqualError(t, err, `cannot encode *(regexStruct).EmailField (string): string "bob@io.net" does not match regex ^[a-z]{1,8}@[a-z]+\.(com|org)$`)
+
+	// Repeats without a maximum are limited to maxRegexRepeat
+	_, err = Encode(&regexStruct{EmailField: "bob@abcdefghijkl.com", VersionField: "v1.0"})
+	assert.EqualError(t, err, `cannot encode *(regexStruct).EmailField (string): string "bob@abcdefghijkl.com" does not match regex ^[a-z]{1,8}@[a-z]+\.(com|org)$`)
+}
+
+type nestedRegexStruct struct {
+	StringField string `fuzz-regex:"^(a*)*b$"`
+}
+
+type assertionRegexStruct struct {
+	StringField string `fuzz-regex:"[a-c]$b"`
+}
+
+// Regexes with nested repeats are matched without an exponential search
+func TestEncode_RegexNestedRepeats(t *testing.T) {
+	val := nestedRegexStruct{StringField: strings.Repeat("a", 40) + "b"}
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	filled := nestedRegexStruct{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+
+	_, err = Encode(&nestedRegexStruct{StringField: strings.Repeat("a", 40)})
+	assert.Error(t, err)
+
+	// Too many a's for the limit on repeats
+	_, err = Encode(&nestedRegexStruct{StringField: strings.Repeat("a", 200) + "b"})
+	assert.Error(t, err)
+}
+
+// Assertions are ignored when making strings, so strings which the regexp
+// package would not match are encoded
+func TestEncode_RegexAssertions(t *testing.T) {
+	val := assertionRegexStruct{StringField: "ab"}
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	filled := assertionRegexStruct{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+}
+
+type badRegexSyntax struct {
+	StringField string `fuzz-regex:"[a-z"`
+}
+
+type badRegexNotString struct {
+	IntField int `fuzz-regex:"[a-z]"`
+}
+
+type badRegexCharset struct {
+	StringField string `fuzz-regex:"[a-z]" fuzz-charset:"hex"`
+}
+
+func TestFill_BadRegexTags(t *testing.T) {
+	assert.PanicsWithError(t, "fuzzhelper.badRegexSyntax.StringField has invalid fuzz-regex \"[a-z\", error parsing regexp: missing closing ]: `[a-z`", func() {
+		Fill(&badRegexSyntax{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badRegexNotString.IntField has fuzz-regex, but int does not contain a string", func() {
+		Fill(&badRegexNotString{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badRegexCharset.StringField has fuzz-regex, which can't be used with fuzz-charset, fuzz-string-exact, fuzz-string-raw or fuzz-string-method", func() {
+		Fill(&badRegexCharset{}, []byte{1})
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -28,40 +29,594 @@ type fuzzTags struct {
 	floatValues     methodTag[[]float64]
 	stringValues    methodTag[[]string]
 	interfaceValues methodTag[[]any]
+
+	// The weights of the options of the method tag, if set
+	weights weightsTag
+
+	// Numbers are edge biased, see WithEdgeBias
+	edgeBias bool
+
+	floatSpecial  floatSpecialTag
+	floatDecimals floatDecimalsTag
+
+	// How often a pointer or interface is nil, see WithNilPercent
+	nilPercent nilTag
+
+	// The depth limit, and the interface options used at the limit, see
+	// WithMaxDepth
+	maxDepth   maxDepthTag
+	leafValues methodTag[[]any]
+
+	// Pointers may reuse previously allocated pointers, see WithAliasing
+	alias boolTag
+
+	// The keys of a map are distinct, see WithDistinctMapKeys
+	distinctKeys boolTag
+
+	// The elements of a slice or array are arranged, see arrangeElements
+	unique      boolTag
+	sorted      boolTag
+	sortMethod  sortMethodTag
+	permutation boolTag
+
+	// A []byte takes the rest of the input, see newBytesTags
+	rest boolTag
+
+	// How the runes of a string are made, see newCharsetTag
+	charset charsetTag
+
+	// Strings match a regular expression, see newRegexTag
+	regex regexTag
+
+	// The tags for the keys and values of a map, set by the fuzz-key- and
+	// fuzz-value- tags. If nil the keys or values use these tags.
+	keyTags   *fuzzTags
+	valueTags *fuzzTags
+
+	// The tags for the elements of slices, arrays and maps, set when the
+	// field's tags have levels, see tagLevels. If nil the elements use
+	// these tags.
+	elemTags *fuzzTags
+	// Tags with levels are passed on to the values pointers point to
+	leveled bool
+
+	// A description of every fuzz tag which affects how values are built,
+	// used to fingerprint the layout of a type
+	layout string
 }
 
 func newFuzzTags(structVal reflect.Value, field reflect.StructField) fuzzTags {
+	levels := tagLevels(field.Tag)
+	if levels == nil {
+		t := newFieldFuzzTags(structVal, field)
+		validateFilledTypes(structVal, field, "fuzz-", t)
+		return t
+	}
+
+	// Build the tags for each level, the type of each level is the type
+	// of the elements of the level above
+	levelTags := []fuzzTags{}
+	levelFields := []reflect.StructField{}
+	levelField := field
+	for _, tag := range levels {
+		levelField.Tag = tag
+		t := newFieldFuzzTags(structVal, levelField)
+		t.leveled = true
+		levelTags = append(levelTags, t)
+		levelFields = append(levelFields, levelField)
+		levelField.Type = levelElemType(levelField.Type)
+	}
+
+	layout := newTagsLayout(levelTags[0], field)
+	for i := len(levelTags) - 1; i > 0; i-- {
+		layout += strings.Join(appendMethodLayouts([]string{}, fmt.Sprintf(" level%d-", i), levelTags[i]), "")
+		elemTags := levelTags[i]
+		levelTags[i-1].elemTags = &elemTags
+	}
+	for i, t := range levelTags {
+		validateFilledTypes(structVal, levelFields[i], "fuzz-", t)
+	}
+	levelTags[0].layout = layout
+	return levelTags[0]
+}
+
+func newFieldFuzzTags(structVal reflect.Value, field reflect.StructField) fuzzTags {
+	t := newPrefixedFuzzTags(structVal, field, "fuzz-")
+	t.keyTags, t.valueTags = newMapFuzzTags(structVal, field)
+	t.layout = newTagsLayout(t, field)
+	return t
+}
+
+// Fuzz tags can have a different value for each level of nested slices,
+// arrays and maps, separated by ";". e.g. a [][]int with
+// fuzz-slice-range:"1,3;0,10" has between 1 and 3 inner slices, each with
+// up to 10 ints. The first value is used for the field, the second for its
+// elements and so on. An empty value leaves the tag unset at that level, as
+// does a missing value for a level deeper than the last value. Pointers are
+// not a level, the value they point to uses the same level as the pointer.
+// The keys of a map use the same level as the map, and its values the next
+// level.
+//
+// Only the range, nil, max-depth, bias and true/false tags, see levelTagNames,
+// take levels. The values of every other tag, such as a regex or charset
+// which may contain ";", are never split. Tags with a single value apply at
+// every level, as they would if the field had no tags with levels.
+//
+// Without levels, the value a pointer points to is filled without tags, just
+// as the pointer would be if it were the element of a slice with tags. With
+// levels the tags of the pointer's level are passed through to the value it
+// points to, see fuzzTags.pointeeTags.
+//
+// Returns the tags for each level, the last of which is used for every
+// deeper level. Returns nil if no fuzz tag has more than one level.
+func tagLevels(tag reflect.StructTag) []reflect.StructTag {
+	type levelTag struct {
+		name   string
+		values []string
+	}
+
+	tags := []levelTag{}
+	depth := 0
+	for _, name := range fuzzTagNames(tag) {
+		value, _ := tag.Lookup(name)
+		values := []string{value}
+		if takesLevels(name) && strings.Contains(value, ";") {
+			values = strings.Split(value, ";")
+			depth = max(depth, len(values))
+		}
+		tags = append(tags, levelTag{name: name, values: values})
+	}
+	if depth == 0 {
+		return nil
+	}
+
+	levels := []reflect.StructTag{}
+	for level := 0; level <= depth; level++ {
+		parts := []string{}
+		for _, t := range tags {
+			value := t.values[0]
+			if len(t.values) > 1 {
+				if level >= len(t.values) || t.values[level] == "" {
+					continue
+				}
+				value = t.values[level]
+			}
+			parts = append(parts, fmt.Sprintf("%s:%s", t.name, strconv.Quote(value)))
+		}
+		levels = append(levels, reflect.StructTag(strings.Join(parts, " ")))
+	}
+	return levels
+}
+
+// The fuzz tags, without their fuzz-, fuzz-key- or fuzz-value- prefix, whose
+// values can be split into levels
+var levelTagNames = map[string]bool{
+	"int-range":    true,
+	"uint-range":   true,
+	"float-range":  true,
+	"string-range": true,
+	"slice-range":  true,
+	"map-range":    true,
+	"bytes-range":  true,
+	"nil":          true,
+	"max-depth":    true,
+	"bias":         true,
+	"alias":        true,
+	"map-distinct": true,
+	"unique":       true,
+	"sorted":       true,
+	"permutation":  true,
+	"rest":         true,
+	"string-exact": true,
+	"string-raw":   true,
+}
+
+// Returns true if the fuzz tag name can have a value for each level
+func takesLevels(name string) bool {
+	for _, prefix := range []string{"fuzz-key-", "fuzz-value-", "fuzz-"} {
+		if base, ok := strings.CutPrefix(name, prefix); ok {
+			return levelTagNames[base]
+		}
+	}
+	return false
+}
+
+// Returns the type of the elements of the slice, array or map (the map's
+// values) in typ, looking through pointers. Returns typ if there is none.
+func levelElemType(typ reflect.Type) reflect.Type {
+	elemType := typ
+	for elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	switch elemType.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return elemType.Elem()
+	default:
+		return typ
+	}
+}
+
+// Builds the tags of field whose names start with prefix, e.g. with the
+// prefix prefix+"key-" the tag fuzz-key-int-range is the int range.
+func newPrefixedFuzzTags(structVal reflect.Value, field reflect.StructField, prefix string) fuzzTags {
 	t := newEmptyFuzzTags()
 
 	t.fieldName = field.Name
 
-	t.intRange = newIntTagRange(field, "fuzz-int-range")
-	t.uintRange = newUintTagRange(field, "fuzz-uint-range")
-	t.floatRange = newFloatTagRange(field, "fuzz-float-range")
-	t.stringRange = newLengthTagRangeWithDefault(field, "fuzz-string-range", defaultLengthMin, defaultLengthMax)
-	t.sliceRange = newLengthTagRange(field, "fuzz-slice-range")
-	t.mapRange = newLengthTagRangeWithDefault(field, "fuzz-map-range", defaultLengthMin, defaultLengthMax)
+	t.intRange = newIntTagRange(structVal, field, prefix+"int-range")
+	t.uintRange = newUintTagRange(structVal, field, prefix+"uint-range")
+	t.floatRange = newFloatTagRange(structVal, field, prefix+"float-range")
+	t.stringRange = newLengthTagRangeWithDefault(structVal, field, prefix+"string-range", defaultLengthMin, defaultLengthMax)
+	t.sliceRange = newLengthTagRange(structVal, field, prefix+"slice-range")
+	t.mapRange = newLengthTagRangeWithDefault(structVal, field, prefix+"map-range", defaultLengthMin, defaultLengthMax)
+
+	t.intValues = newMethodTag[int64](structVal, field, prefix+"int-method")
+	t.uintValues = newMethodTag[uint64](structVal, field, prefix+"uint-method")
+	t.floatValues = newMethodTag[float64](structVal, field, prefix+"float-method")
+	t.stringValues = newMethodTag[string](structVal, field, prefix+"string-method")
+	t.interfaceValues = newMethodTag[any](structVal, field, prefix+"interface-method")
+
+	t.weights = newWeightsTag(structVal, field, prefix+"weights", t)
+
+	t.edgeBias = newBiasTag(structVal, field, prefix+"bias")
+
+	t.floatSpecial = newFloatSpecialTag(structVal, field, prefix+"float-special")
+	t.floatDecimals = newFloatDecimalsTag(structVal, field, prefix+"float-decimals")
 
-	t.intValues = newMethodTag[int64](structVal, field, "fuzz-int-method")
-	t.uintValues = newMethodTag[uint64](structVal, field, "fuzz-uint-method")
-	t.floatValues = newMethodTag[float64](structVal, field, "fuzz-float-method")
-	t.stringValues = newMethodTag[string](structVal, field, "fuzz-string-method")
-	t.interfaceValues = newMethodTag[any](structVal, field, "fuzz-interface-method")
+	t.nilPercent = newNilTag(structVal, field, prefix+"nil")
+
+	t.maxDepth = newMaxDepthTag(structVal, field, prefix+"max-depth")
+	t.leafValues = newMethodTag[any](structVal, field, prefix+"leaf-method")
+
+	t.alias = newBoolTag(structVal, field, prefix+"alias")
+
+	t.distinctKeys = newBoolTag(structVal, field, prefix+"map-distinct")
+
+	t.unique = newBoolTag(structVal, field, prefix+"unique")
+	t.sorted = newBoolTag(structVal, field, prefix+"sorted")
+	t.sortMethod = newSortMethodTag(structVal, field, prefix+"sort-method")
+	t.permutation = newBoolTag(structVal, field, prefix+"permutation")
+	newBytesTags(structVal, field, prefix, &t)
+
+	t.charset = newCharsetTag(structVal, field, prefix)
+	t.regex = newRegexTag(structVal, field, prefix, t)
+	validateElementTags(structVal, field, prefix, t)
 
 	return t
 }
 
+// Builds a description of the fuzz tags on a field. Field names, method
+// names and fuzz-id tags don't change the way bytes are consumed and are
+// left out. The values returned by methods are included as changing them
+// changes how bytes are interpreted.
+func newTagsLayout(t fuzzTags, field reflect.StructField) string {
+	parts := []string{}
+	for _, tag := range fuzzTagNames(field.Tag) {
+		if tag == "fuzz-id" || strings.HasSuffix(tag, "-method") {
+			continue
+		}
+		value, _ := field.Tag.Lookup(tag)
+		parts = append(parts, fmt.Sprintf("%s:%q", tag, value))
+	}
+
+	parts = appendMethodLayouts(parts, "", t)
+	if t.keyTags != nil {
+		parts = appendMethodLayouts(parts, "key-", *t.keyTags)
+	}
+	if t.valueTags != nil {
+		parts = appendMethodLayouts(parts, "value-", *t.valueTags)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func appendMethodLayouts(parts []string, prefix string, t fuzzTags) []string {
+	parts = appendMethodLayout(parts, prefix, t.intValues)
+	parts = appendMethodLayout(parts, prefix, t.uintValues)
+	parts = appendMethodLayout(parts, prefix, t.floatValues)
+	parts = appendMethodLayout(parts, prefix, t.stringValues)
+	parts = appendInterfaceLayout(parts, prefix+"interface-options", t.interfaceValues)
+	parts = appendInterfaceLayout(parts, prefix+"leaf-options", t.leafValues)
+	return parts
+}
+
+func appendInterfaceLayout(parts []string, name string, tag methodTag[[]any]) []string {
+	if !tag.wasSet {
+		return parts
+	}
+	types := []string{}
+	for _, value := range tag.value {
+		types = append(types, reflect.TypeOf(value).String())
+	}
+	return append(parts, fmt.Sprintf("%s:%v", name, types))
+}
+
+func appendMethodLayout[T any](parts []string, prefix string, tag methodTag[[]T]) []string {
+	if !tag.wasSet {
+		return parts
+	}
+	return append(parts, fmt.Sprintf("%soptions:%v", prefix, tag.value))
+}
+
+// Returns the names of all of the tags starting with "fuzz-", in the order
+// they appear. The parsing follows reflect.StructTag.Lookup.
+func fuzzTagNames(tag reflect.StructTag) []string {
+	names := []string{}
+	for tag != "" {
+		// Skip leading space.
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := string(tag[:i])
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		tag = tag[i+1:]
+
+		if strings.HasPrefix(name, "fuzz-") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Returns the indices of the fields of structType in the order they should be
+// visited.
+//
+// Fields with a fuzz-id tag are visited first, in ascending id order,
+// followed by any fields without a fuzz-id in the order they are declared.
+// Giving fields ids keeps the byte layout of a struct stable when its fields
+// are reordered or renamed.
+func fieldOrder(structType reflect.Type) []int {
+	type idField struct {
+		id    int
+		index int
+	}
+
+	withIds := []idField{}
+	withoutIds := []int{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		idStr, ok := field.Tag.Lookup("fuzz-id")
+		if !ok {
+			withoutIds = append(withoutIds, i)
+			continue
+		}
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id < 0 {
+			panic(fmt.Errorf("%s.%s has invalid fuzz-id %q, must be a non-negative integer", structType, field.Name, idStr))
+		}
+
+		for _, other := range withIds {
+			if other.id == id {
+				panic(fmt.Errorf("%s.%s and %s.%s have the same fuzz-id %d", structType, structType.Field(other.index).Name, structType, field.Name, id))
+			}
+		}
+
+		withIds = append(withIds, idField{id: id, index: i})
+	}
+
+	slices.SortFunc(withIds, func(a, b idField) int {
+		return a.id - b.id
+	})
+
+	order := make([]int, 0, structType.NumField())
+	for _, field := range withIds {
+		order = append(order, field.index)
+	}
+	return append(order, withoutIds...)
+}
+
 func newEmptyFuzzTags() fuzzTags {
 	return fuzzTags{}
 }
 
+// Returns the tags for the keys and values of the map in field, if it has
+// any fuzz-key- or fuzz-value- tags.
+func newMapFuzzTags(structVal reflect.Value, field reflect.StructField) (keyTags, valueTags *fuzzTags) {
+	hasKeyTags, hasValueTags := false, false
+	for _, name := range fuzzTagNames(field.Tag) {
+		hasKeyTags = hasKeyTags || strings.HasPrefix(name, "fuzz-key-")
+		hasValueTags = hasValueTags || strings.HasPrefix(name, "fuzz-value-")
+	}
+	if !hasKeyTags && !hasValueTags {
+		return nil, nil
+	}
+
+	mapType := containedMap(field.Type)
+	if mapType == nil {
+		panic(fmt.Errorf("%s.%s has fuzz-key- or fuzz-value- tags, but %s does not contain a map", structVal.Type(), field.Name, field.Type))
+	}
+
+	if hasKeyTags {
+		keyField := field
+		keyField.Type = mapType.Key()
+		tags := newPrefixedFuzzTags(structVal, keyField, "fuzz-key-")
+		validateFilledTypes(structVal, keyField, "fuzz-key-", tags)
+		keyTags = &tags
+	}
+	if hasValueTags {
+		valueField := field
+		valueField.Type = mapType.Elem()
+		tags := newPrefixedFuzzTags(structVal, valueField, "fuzz-value-")
+		validateFilledTypes(structVal, valueField, "fuzz-value-", tags)
+		valueTags = &tags
+	}
+	return keyTags, valueTags
+}
+
+// Panics if the tags of t, the tags of field starting with prefix, can't be
+// used for every type they fill. An int or uint range must have values of
+// the type, and the options of a method tag must be assignable to it. The
+// values filled would otherwise wrap around silently, see
+// intTagRange.within.
+func validateFilledTypes(structVal reflect.Value, field reflect.StructField, prefix string, t fuzzTags) {
+	for _, typ := range rangeTypes(field.Type, t) {
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			typeMin, typeMax := intTypeBounds(typ)
+			if _, ok := clampIntervals(t.intRange.parts(), typeMin, typeMax); t.intRange.valid() && !ok {
+				panic(fmt.Errorf("%s.%s has %sint-range %q, which has no values of type %s", structVal.Type(), field.Name, prefix, field.Tag.Get(prefix+"int-range"), typ))
+			}
+			validateMethodType(structVal, field, t.intValues.options, t.intValues.methodName, typ)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if _, ok := clampIntervals(t.uintRange.parts(), 0, uintTypeMax(typ)); t.uintRange.valid() && !ok {
+				panic(fmt.Errorf("%s.%s has %suint-range %q, which has no values of type %s", structVal.Type(), field.Name, prefix, field.Tag.Get(prefix+"uint-range"), typ))
+			}
+			validateMethodType(structVal, field, t.uintValues.options, t.uintValues.methodName, typ)
+		case reflect.Float32, reflect.Float64:
+			validateMethodType(structVal, field, t.floatValues.options, t.floatValues.methodName, typ)
+		case reflect.String:
+			validateMethodType(structVal, field, t.stringValues.options, t.stringValues.methodName, typ)
+		case reflect.Interface:
+			validateMethodType(structVal, field, t.interfaceValues.options, t.interfaceValues.methodName, typ)
+			validateMethodType(structVal, field, t.leafValues.options, t.leafValues.methodName, typ)
+		}
+	}
+}
+
+// Panics if any of options, the values returned by a method tag's method,
+// can't be assigned to typ. A tag on a map is only checked against one of
+// the key and value types when it is parsed, see isAssignable, so this
+// checks each type the options are used for.
+func validateMethodType(structVal reflect.Value, field reflect.StructField, options reflect.Value, methodName string, typ reflect.Type) {
+	if !options.IsValid() {
+		return
+	}
+	for i := range options.Len() {
+		if err := isAssignable(options.Index(i), typ); err != nil {
+			panic(fmt.Errorf("%s.%s cannot be assigned by every value returned by %s.%s(), %w", structVal.Type(), field.Name, structVal.Type(), methodName, err))
+		}
+	}
+}
+
+// Returns the types of the values filled with t in a value of typ, looking
+// through the pointers, slices, arrays and maps which pass t on
+func rangeTypes(typ reflect.Type, t fuzzTags) []reflect.Type {
+	switch typ.Kind() {
+	case reflect.Pointer:
+		if !t.leveled {
+			return nil
+		}
+		return rangeTypes(typ.Elem(), t)
+	case reflect.Slice, reflect.Array:
+		if t.elemTags != nil {
+			return nil
+		}
+		return rangeTypes(typ.Elem(), t)
+	case reflect.Map:
+		types := []reflect.Type{}
+		if t.keyTags == nil {
+			types = append(types, rangeTypes(typ.Key(), t)...)
+		}
+		if t.valueTags == nil && t.elemTags == nil {
+			types = append(types, rangeTypes(typ.Elem(), t)...)
+		}
+		return types
+	default:
+		return []reflect.Type{typ}
+	}
+}
+
+// Returns the first map type found in typ, looking through pointers, slices
+// and arrays. Returns nil if there is no map.
+func containedMap(typ reflect.Type) reflect.Type {
+	for {
+		switch typ.Kind() {
+		case reflect.Map:
+			return typ
+		case reflect.Pointer, reflect.Slice, reflect.Array:
+			typ = typ.Elem()
+		default:
+			return nil
+		}
+	}
+}
+
+// Returns the tags used to fill the keys of a map filled with t
+func (t fuzzTags) mapKeyTags() fuzzTags {
+	if t.keyTags != nil {
+		return *t.keyTags
+	}
+	return t
+}
+
+// Returns the tags used to fill the values of a map filled with t
+func (t fuzzTags) mapValueTags() fuzzTags {
+	if t.valueTags != nil {
+		return *t.valueTags
+	}
+	return t.elementTags()
+}
+
+// Returns the tags used to fill the elements of a slice or array filled
+// with t
+func (t fuzzTags) elementTags() fuzzTags {
+	if t.elemTags != nil {
+		return *t.elemTags
+	}
+	return t
+}
+
+// Returns the tags used to fill the value pointed to by a pointer filled
+// with t
+func (t fuzzTags) pointeeTags() fuzzTags {
+	if t.leveled {
+		return t
+	}
+	return newEmptyFuzzTags()
+}
+
+// The fuzz range tags hold one or more "min,max" intervals separated by "|",
+// e.g. "0,10|100,200". If min or max are omitted the interval is bounded by
+// the limit of the value's type. Ints and uints are parsed as decimal, or as
+// hexadecimal, binary or octal with a 0x, 0b or 0o prefix, and may use
+// underscores, e.g. "0x00,0xFF" or "0,1_000". Leading zeros don't change the
+// base, so "010" is ten. Floats are parsed as Go float literals, or as integer
+// literals. A malformed range panics. Ranged ints and uints are filled evenly
+// from their range, a value consumed past the last whole multiple of the
+// range's size is rejected and another value is consumed.
+// A float range with several intervals consumes a choice of interval before
+// its value, so reserved values such as "0,10|100,100" are filled as often
+// as any other interval. Values outside the value's type are left out of an
+// int or uint range, and a range with no values of the type panics.
+
 type intTagRange struct {
 	wasSet bool
+	// The smallest and largest values in the range
 	intMin int64
 	intMax int64
+	// The intervals which make up the range, sorted without overlaps. If
+	// nil the range is the single interval from intMin to intMax.
+	intervals []interval[int64]
 }
 
-func newIntTagRange(field reflect.StructField, tag string) intTagRange {
+func newIntTagRange(structVal reflect.Value, field reflect.StructField, tag string) intTagRange {
 	//println(field.Tag)
 
 	valStr, ok := field.Tag.Lookup(tag)
@@ -70,73 +625,115 @@ func newIntTagRange(field reflect.StructField, tag string) intTagRange {
 		return intTagRange{}
 	}
 
-	parts := strings.Split(valStr, ",")
-	if len(parts) != 2 {
-		//println("bad min max tag", valStr)
-		return intTagRange{}
+	parse := func(s string) (int64, error) {
+		return strconv.ParseInt(s, intLiteralBase(s), 64)
 	}
-
-	minVal, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		//println("bad min tag value", valStr)
-		return intTagRange{}
+	openMax := func(int64) int64 {
+		return math.MaxInt64
 	}
-
-	maxVal, err := strconv.ParseInt(parts[1], 10, 64)
-	if err != nil {
-		//println("bad max tag value", valStr)
-		return intTagRange{}
+	intervals, ok := parseIntervals(valStr, parse, math.MinInt64, openMax)
+	if !ok {
+		panic(badRangeError(structVal, field, tag, valStr))
 	}
 
-	//println("int64 min max", tag, minVal, maxVal)
+	return newIntTagRangeFromIntervals(intervals)
+}
+
+func newIntTagRangeFromIntervals(intervals []interval[int64]) intTagRange {
 	return intTagRange{
-		wasSet: true,
-		intMin: minVal,
-		intMax: maxVal,
+		wasSet:    true,
+		intMin:    intervals[0].lo,
+		intMax:    intervals[len(intervals)-1].hi,
+		intervals: intervals,
 	}
 }
 
-func (r *intTagRange) fit(val int64) int64 {
-	if !r.wasSet {
-		return val
+// Returns true if the range is set and its minimum is not greater than its
+// maximum
+func (r *intTagRange) valid() bool {
+	return r.wasSet && r.intMin <= r.intMax
+}
+
+func (r *intTagRange) parts() []interval[int64] {
+	if r.intervals == nil {
+		return []interval[int64]{{lo: r.intMin, hi: r.intMax}}
 	}
+	return r.intervals
+}
 
-	if r.intMax == r.intMin {
-		// If min/max are the same the value is clamped to that value
-		return r.intMax
+// Returns the range narrowed to the values which fit in value's type. This
+// allows every value of the range to be reached from value.Type().Size()
+// bytes. The range is returned unchanged if it is not valid or contains no
+// values of value's type.
+func (r intTagRange) within(value reflect.Value) intTagRange {
+	if !r.valid() {
+		return r
 	}
 
-	if r.intMax <= r.intMin {
-		// Our min/max values are incorrectly set up
-		return val
+	typeMin, typeMax := intTypeBounds(value.Type())
+	intervals, ok := clampIntervals(r.parts(), typeMin, typeMax)
+	if !ok {
+		return r
 	}
-	spread := (r.intMax - r.intMin) + 1
+	return newIntTagRangeFromIntervals(intervals)
+}
 
-	fitted := (absInt(val) % spread) + r.intMin
-	//println("int val fitted", val, r.intMin, r.intMax, fitted)
+// Returns the smallest and largest values of typ, an int type
+func intTypeBounds(typ reflect.Type) (int64, int64) {
+	typeMin := int64(-1) << (typ.Bits() - 1)
+	return typeMin, -(typeMin + 1)
+}
 
-	return fitted
+// Returns the largest value of typ, a uint type
+func uintTypeMax(typ reflect.Type) uint64 {
+	return uint64(math.MaxUint64) >> (64 - typ.Bits())
 }
 
-func absInt(val int64) int64 {
-	if val == math.MinInt64 {
-		// taking -math.MinInt64 produces math.MinInt64
-		// So we need to special case this value
-		return math.MaxInt64
+// Returns the number of values in the range. Returns 0 if the range is not
+// set, is not valid or covers every int64 value.
+func (r *intTagRange) span() uint64 {
+	if !r.valid() {
+		return 0
 	}
-	if val < 0 {
-		return -val
-	}
-	return val
+	return intervalsSpan(r.parts())
+}
+
+// Maps offset into the range, as the offset from the range minimum. The
+// arithmetic is unsigned, so ranges wider than math.MaxInt64 don't
+// overflow. A range covering every int64 value maps every offset to a
+// distinct value.
+func (r *intTagRange) fitOffset(offset uint64) int64 {
+	return intervalsFitOffset(r.parts(), offset)
+}
+
+// Returns the offset of val from the range minimum, the inverse of
+// fitOffset. Returns false if val is not in the range.
+func (r *intTagRange) offset(val int64) (uint64, bool) {
+	return intervalsOffset(r.parts(), val)
+}
+
+func (r *intTagRange) contains(val int64) bool {
+	return intervalsContain(r.parts(), val)
 }
 
 type uintTagRange struct {
-	wasSet  bool
+	wasSet bool
+	// The smallest and largest values in the range
 	uintMin uint64
 	uintMax uint64
+	// The intervals which make up the range, sorted without overlaps. If
+	// nil the range is the single interval from uintMin to uintMax.
+	intervals []interval[uint64]
+}
+
+func newUintTagRange(structVal reflect.Value, field reflect.StructField, tag string) uintTagRange {
+	return newUintTagRangeWithOpenMax(structVal, field, tag, func(uint64) uint64 {
+		return math.MaxUint64
+	})
 }
 
-func newUintTagRange(field reflect.StructField, tag string) uintTagRange {
+// Parses a uint range, where an omitted maximum is given by openMax(min)
+func newUintTagRangeWithOpenMax(structVal reflect.Value, field reflect.StructField, tag string, openMax func(uint64) uint64) uintTagRange {
 	//println(field.Tag)
 
 	valStr, ok := field.Tag.Lookup(tag)
@@ -145,60 +742,91 @@ func newUintTagRange(field reflect.StructField, tag string) uintTagRange {
 		return uintTagRange{}
 	}
 
-	parts := strings.Split(valStr, ",")
-	if len(parts) != 2 {
-		//println("bad min max tag", valStr)
-		return uintTagRange{}
+	parse := func(s string) (uint64, error) {
+		return strconv.ParseUint(s, intLiteralBase(s), 64)
 	}
-
-	minVal, err := strconv.ParseUint(parts[0], 10, 64)
-	if err != nil {
-		//println("bad min tag value", valStr)
-		return uintTagRange{}
+	intervals, ok := parseIntervals(valStr, parse, 0, openMax)
+	if !ok {
+		panic(badRangeError(structVal, field, tag, valStr))
 	}
 
-	maxVal, err := strconv.ParseUint(parts[1], 10, 64)
-	if err != nil {
-		//println("bad max tag value", valStr)
-		return uintTagRange{}
-	}
+	return newUintTagRangeFromIntervals(intervals)
+}
 
-	//println("uint64 min max", tag, minVal, maxVal)
+func newUintTagRangeFromIntervals(intervals []interval[uint64]) uintTagRange {
 	return uintTagRange{
-		wasSet:  true,
-		uintMin: minVal,
-		uintMax: maxVal,
+		wasSet:    true,
+		uintMin:   intervals[0].lo,
+		uintMax:   intervals[len(intervals)-1].hi,
+		intervals: intervals,
 	}
 }
 
 func (r *uintTagRange) fit(val uint64) uint64 {
-	if !r.wasSet {
+	if !r.valid() {
 		return val
 	}
+	return r.fitOffset(val)
+}
 
-	if r.uintMax == r.uintMin {
-		// If min/max are the same the value is clamped to that value
-		return r.uintMax
+// Returns true if the range is set and its minimum is not greater than its
+// maximum
+func (r *uintTagRange) valid() bool {
+	return r.wasSet && r.uintMin <= r.uintMax
+}
+
+func (r *uintTagRange) parts() []interval[uint64] {
+	if r.intervals == nil {
+		return []interval[uint64]{{lo: r.uintMin, hi: r.uintMax}}
 	}
+	return r.intervals
+}
 
-	if r.uintMax <= r.uintMin {
-		// Our min/max values are incorrectly set up
-		return val
+// Returns the range narrowed to the values which fit in value's type, see
+// intTagRange.within
+func (r uintTagRange) within(value reflect.Value) uintTagRange {
+	if !r.valid() {
+		return r
 	}
-	spread := (r.uintMax - r.uintMin) + 1
 
-	fitted := (val % spread) + r.uintMin
-	//println("uint val fitted", val, r.uintMin, r.uintMax, fitted)
+	intervals, ok := clampIntervals(r.parts(), 0, uintTypeMax(value.Type()))
+	if !ok {
+		return r
+	}
+	return newUintTagRangeFromIntervals(intervals)
+}
 
-	return fitted
+// Returns the number of values in the range. Returns 0 if the range is not
+// set, is not valid or covers every uint64 value.
+func (r *uintTagRange) span() uint64 {
+	if !r.valid() {
+		return 0
+	}
+	return intervalsSpan(r.parts())
+}
+
+// Maps offset into the range, as the offset from the range minimum. A range
+// covering every uint64 value maps every offset to a distinct value.
+func (r *uintTagRange) fitOffset(offset uint64) uint64 {
+	return intervalsFitOffset(r.parts(), offset)
+}
+
+// Returns the offset of val from the range minimum, the inverse of
+// fitOffset. Returns false if val is not in the range.
+func (r *uintTagRange) offset(val uint64) (uint64, bool) {
+	return intervalsOffset(r.parts(), val)
+}
+
+func (r *uintTagRange) contains(val uint64) bool {
+	return intervalsContain(r.parts(), val)
 }
 
 type lengthTagRange struct {
 	uintRange uintTagRange
 }
 
-func newLengthTagRangeWithDefault(field reflect.StructField, tag string, defaultMin, defaultMax uint64) lengthTagRange {
-	r := newLengthTagRange(field, tag)
+func newLengthTagRangeWithDefault(structVal reflect.Value, field reflect.StructField, tag string, defaultMin, defaultMax uint64) lengthTagRange {
+	r := newLengthTagRange(structVal, field, tag)
 	if !r.uintRange.wasSet {
 		r.uintRange = uintTagRange{
 			wasSet:  true,
@@ -210,9 +838,13 @@ func newLengthTagRangeWithDefault(field reflect.StructField, tag string, default
 	return r
 }
 
-func newLengthTagRange(field reflect.StructField, tag string) lengthTagRange {
+// An omitted maximum length is defaultLengthMax more than the minimum
+// length. Allowing lengths up to the limit of an int would exhaust memory.
+func newLengthTagRange(structVal reflect.Value, field reflect.StructField, tag string) lengthTagRange {
 	return lengthTagRange{
-		uintRange: newUintTagRange(field, tag),
+		uintRange: newUintTagRangeWithOpenMax(structVal, field, tag, func(minVal uint64) uint64 {
+			return minVal + defaultLengthMax
+		}),
 	}
 }
 
@@ -225,12 +857,16 @@ func (r *lengthTagRange) fit(val int) int {
 }
 
 type floatTagRange struct {
-	wasSet   bool
+	wasSet bool
+	// The smallest and largest values in the range
 	floatMin float64
 	floatMax float64
+	// The intervals which make up the range, sorted without overlaps. If
+	// nil the range is the single interval from floatMin to floatMax.
+	intervals []interval[float64]
 }
 
-func newFloatTagRange(field reflect.StructField, tag string) floatTagRange {
+func newFloatTagRange(structVal reflect.Value, field reflect.StructField, tag string) floatTagRange {
 	//println(field.Tag)
 
 	valStr, ok := field.Tag.Lookup(tag)
@@ -239,34 +875,114 @@ func newFloatTagRange(field reflect.StructField, tag string) floatTagRange {
 		return floatTagRange{}
 	}
 
-	parts := strings.Split(valStr, ",")
-	if len(parts) != 2 {
-		//println("bad min max tag", valStr)
-		return floatTagRange{}
+	openMax := func(float64) float64 {
+		return math.MaxFloat64
+	}
+	intervals, ok := parseIntervals(valStr, parseFloatLiteral, -math.MaxFloat64, openMax)
+	if !ok {
+		panic(badRangeError(structVal, field, tag, valStr))
 	}
 
-	minVal, err := strconv.ParseFloat(parts[0], 64)
-	if err != nil {
-		//println("bad min tag value", valStr)
-		return floatTagRange{}
+	return newFloatTagRangeFromIntervals(intervals)
+}
+
+// Returns the error for a range tag which can't be parsed
+func badRangeError(structVal reflect.Value, field reflect.StructField, tag, valStr string) error {
+	return fmt.Errorf("%s.%s has invalid %s %q, must be one or more min,max intervals separated by |", structVal.Type(), field.Name, tag, valStr)
+}
+
+// Returns the base for strconv to parse the integer literal s with.
+// Literals with a 0x, 0b or 0o prefix are parsed as Go integer literals.
+// Every other literal is decimal, so a leading zero doesn't make it octal.
+func intLiteralBase(s string) int {
+	digits := strings.TrimLeft(s, "+-")
+	if len(digits) > 1 && digits[0] == '0' && strings.ContainsRune("xXbBoO", rune(digits[1])) {
+		return 0
+	}
+	if len(digits) > 0 && digits[0] != '0' {
+		// Decimal, base 0 allows underscores between digits
+		return 0
 	}
+	return 10
+}
 
-	maxVal, err := strconv.ParseFloat(parts[1], 64)
-	if err != nil {
-		//println("bad max tag value", valStr)
-		return floatTagRange{}
+// Parses a Go float literal, or a Go integer literal such as 0xFF
+func parseFloatLiteral(s string) (float64, error) {
+	val, err := strconv.ParseFloat(s, 64)
+	if err == nil {
+		return val, nil
+	}
+
+	intVal, intErr := strconv.ParseInt(s, intLiteralBase(s), 64)
+	if intErr != nil {
+		return 0, err
 	}
+	return float64(intVal), nil
+}
 
-	//println("float64 min max", tag, minVal, maxVal)
+func newFloatTagRangeFromIntervals(intervals []interval[float64]) floatTagRange {
 	return floatTagRange{
-		wasSet:   true,
-		floatMin: minVal,
-		floatMax: maxVal,
+		wasSet:    true,
+		floatMin:  intervals[0].lo,
+		floatMax:  intervals[len(intervals)-1].hi,
+		intervals: intervals,
 	}
 }
 
+// Returns true if the range is set and its minimum is not greater than its
+// maximum
+func (r *floatTagRange) valid() bool {
+	return r.wasSet && r.floatMin <= r.floatMax
+}
+
+func (r *floatTagRange) parts() []interval[float64] {
+	if r.intervals == nil {
+		return []interval[float64]{{lo: r.floatMin, hi: r.floatMax}}
+	}
+	return r.intervals
+}
+
+// Returns the range narrowed to the values which fit in a float32, if value
+// is a float32. The range is returned unchanged if it is not valid or
+// contains no float32 values.
+func (r floatTagRange) within(value reflect.Value) floatTagRange {
+	if !r.valid() || value.Type().Size() != bytesFor32 {
+		return r
+	}
+
+	intervals, ok := clampIntervals(r.parts(), -math.MaxFloat32, math.MaxFloat32)
+	if !ok {
+		return r
+	}
+	return newFloatTagRangeFromIntervals(intervals)
+}
+
+func (r *floatTagRange) contains(val float64) bool {
+	return intervalsContain(r.parts(), val)
+}
+
+// Returns the total width of the intervals in the range. If the width is
+// too large for a float64 then half of the width is returned, and halved is
+// true.
+func (r *floatTagRange) width() (width float64, halved bool) {
+	for _, i := range r.parts() {
+		width += i.hi - i.lo
+	}
+	if !math.IsInf(width, 0) {
+		return width, false
+	}
+
+	width = 0
+	for _, i := range r.parts() {
+		width += i.hi/2 - i.lo/2
+	}
+	return width, true
+}
+
+// Fits val into the range. A range made of several intervals should first
+// be narrowed to one of them, see interval.
 func (r *floatTagRange) fit(val float64) float64 {
-	if !r.wasSet {
+	if !r.valid() {
 		return val
 	}
 
@@ -275,15 +991,11 @@ func (r *floatTagRange) fit(val float64) float64 {
 		return r.floatMax
 	}
 
-	if r.floatMax <= r.floatMin {
-		// Our min/max values are incorrectly set up
-		return val
-	}
-	spread := (r.floatMax - r.floatMin)
+	width, halved := r.width()
 
-	// If val is not-a-number then just take the mid-point between min and max
+	// If val is not-a-number then just take the mid-point of the range
 	if math.IsNaN(val) {
-		return r.floatMin + (spread / 2)
+		return r.fitWidth(width/2, halved)
 	}
 
 	// If val is positive infinity then take max
@@ -296,16 +1008,84 @@ func (r *floatTagRange) fit(val float64) float64 {
 		return r.floatMin
 	}
 
-	fitted := math.Mod(math.Abs(val), spread) + r.floatMin
+	if halved {
+		val /= 2
+	}
+	fitted := r.fitWidth(math.Mod(math.Abs(val), width), halved)
 	//println("float val fitted", val, r.floatMin, r.floatMax, fitted)
 
 	return fitted
 }
 
+// Maps an offset, measured across the widths of the intervals of the range,
+// to a value in the range
+func (r *floatTagRange) fitWidth(offset float64, halved bool) float64 {
+	for _, i := range r.parts() {
+		width := i.hi - i.lo
+		if halved {
+			width = i.hi/2 - i.lo/2
+		}
+		if offset <= width {
+			if halved {
+				return i.lo + offset*2
+			}
+			return i.lo + offset
+		}
+		offset -= width
+	}
+	return r.floatMax
+}
+
+// Returns the interval at index i of the range, as a range of its own.
+// Ranges made of several intervals choose an interval before fitting a
+// value into it, so an interval holding a single value is chosen as often as
+// any other.
+func (r *floatTagRange) interval(i int) floatTagRange {
+	return newFloatTagRangeFromIntervals(r.parts()[i : i+1])
+}
+
+// Returns the offset, measured across the widths of the intervals of the
+// range, of val. This is the inverse of fit for values in the range.
+// Returns false if val is not in the range.
+func (r *floatTagRange) offset(val float64) (float64, bool) {
+	offset := 0.0
+	for _, i := range r.parts() {
+		if val >= i.lo && val <= i.hi {
+			return offset + (val - i.lo), true
+		}
+		offset += i.hi - i.lo
+	}
+	return 0, false
+}
+
+type boolTag struct {
+	wasSet  bool
+	enabled bool
+}
+
+func newBoolTag(structVal reflect.Value, field reflect.StructField, tag string) boolTag {
+	valStr, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return boolTag{}
+	}
+
+	enabled, err := strconv.ParseBool(valStr)
+	if err != nil {
+		panic(fmt.Errorf("%s.%s has invalid %s %q, must be true or false", structVal.Type(), field.Name, tag, valStr))
+	}
+
+	return boolTag{
+		wasSet:  true,
+		enabled: enabled,
+	}
+}
+
 type methodTag[T any] struct {
 	wasSet     bool
 	methodName string
 	value      T
+	// The slice returned by the method, before its values are converted
+	options reflect.Value
 }
 
 func newMethodTag[T any](structVal reflect.Value, field reflect.StructField, tag string) methodTag[[]T] {
@@ -360,6 +1140,7 @@ func newMethodTag[T any](structVal reflect.Value, field reflect.StructField, tag
 		wasSet:     true,
 		methodName: methodName,
 		value:      typedSlice,
+		options:    result[0],
 	}
 }
 
@@ -384,14 +1165,27 @@ func copyToTypedSlice[T any](srcSlice reflect.Value, assignType reflect.Type) ([
 }
 
 func isAssignable(value reflect.Value, assignType reflect.Type) error {
-	if assignType.Kind() == reflect.Slice {
+	for assignType.Kind() == reflect.Slice || assignType.Kind() == reflect.Array {
 		// If we are assigning to a slice, we want to know if we can
 		// append value to that slice. Get the type of the slice
-		// elements.
-		// TODO, we probably need to do this for maps as well
+		// elements, through any number of nested slices and arrays.
 		assignType = assignType.Elem()
 	}
 
+	if assignType.Kind() == reflect.Map {
+		// If we are assigning to a map, value is used for the keys,
+		// the values or both. It must be assignable to one of them,
+		// each type it fills is checked by validateFilledTypes
+		keyErr := isAssignable(value, assignType.Key())
+		if keyErr == nil {
+			return nil
+		}
+		if isAssignable(value, assignType.Elem()) == nil {
+			return nil
+		}
+		return keyErr
+	}
+
 	if assignType.Kind() == reflect.Interface && value.Kind() == reflect.Interface && value.Elem().Kind() != reflect.Pointer {
 		// We restrict all interface assignments to pointer types only.
 		// This is done _purely_ to simplify the process of determining
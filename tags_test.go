@@ -3,9 +3,11 @@ package fuzzhelper
 import (
 	"math"
 	"math/rand"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type intLimitStruct struct {
@@ -105,17 +107,31 @@ func TestFuzzTags_UintLimits(t *testing.T) {
 
 func TestFuzzTags_UintLimits_Positive(t *testing.T) {
 	c := newByteConsumer([]byte{})
-	// Push a maximum uint value for each field
+	// Push a maximum uint value for each field. Except for the uint8,
+	// whose range of 2 values divides 256, the maximum values are past
+	// the last whole multiple of the range and are drawn again from the
+	// 0 after them.
 	c.pushUint64(math.MaxUint, bytesForNative)
+	c.pushUint64(0, bytesForNative)
 	c.pushUint64(math.MaxUint64, bytesFor64)
+	c.pushUint64(0, bytesFor64)
 	c.pushUint64(math.MaxUint32, bytesFor32)
+	c.pushUint64(0, bytesFor32)
 	c.pushUint64(math.MaxUint16, bytesFor16)
+	c.pushUint64(0, bytesFor16)
 	c.pushUint64(math.MaxUint8, bytesFor8)
 
 	val := uintLimitStruct{}
 	Fill(&val, c.getRawBytes())
 
 	assertUintLimits(t, val)
+	assert.Equal(t, uintLimitStruct{
+		UintField:   10_000,
+		Uint64Field: 1000,
+		Uint32Field: 100,
+		Uint16Field: 10,
+		Uint8Field:  2,
+	}, val)
 }
 
 func TestFuzzTags_UintLimits_Zero(t *testing.T) {
@@ -150,6 +166,233 @@ func assertUintLimits(t *testing.T, val uintLimitStruct) {
 	assert.GreaterOrEqual(t, val.Uint8Field, uint8(1))
 }
 
+func TestFuzzTags_FullWidthRanges(t *testing.T) {
+	type fullWidthStruct struct {
+		IntField  int64  `fuzz-int-range:"-9223372036854775808,9223372036854775807"`
+		UintField uint64 `fuzz-uint-range:"0,18446744073709551615"`
+	}
+
+	for _, raw := range []uint64{0, 1, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64} {
+		c := newByteConsumer([]byte{})
+		c.pushUint64(raw, bytesFor64)
+		c.pushUint64(raw, bytesFor64)
+
+		val := fullWidthStruct{}
+		Fill(&val, c.getRawBytes())
+
+		// Every offset maps to a distinct value
+		assert.Equal(t, int64(raw+1<<63), val.IntField)
+		assert.Equal(t, raw, val.UintField)
+	}
+}
+
+func TestFuzzTags_RangeDistribution(t *testing.T) {
+	type smallStruct struct {
+		Field int8 `fuzz-int-range:"-10,10"`
+	}
+	type wideStruct struct {
+		Field int8 `fuzz-int-range:"-1000,1000"`
+	}
+	type uintStruct struct {
+		Field uint8 `fuzz-uint-range:"100,1000"`
+	}
+
+	small := map[int8]int{}
+	wide := map[int8]int{}
+	uints := map[uint8]int{}
+	for i := range 256 {
+		smallVal := smallStruct{}
+		Fill(&smallVal, []byte{byte(i), 5})
+		small[smallVal.Field]++
+
+		wideVal := wideStruct{}
+		Fill(&wideVal, []byte{byte(i)})
+		wide[wideVal.Field]++
+
+		uintVal := uintStruct{}
+		Fill(&uintVal, []byte{byte(i), 5})
+		uints[uintVal.Field]++
+	}
+
+	// Each of the 21 values is produced by 12 of the first 252 bytes. The
+	// 4 bytes after the last whole multiple of 21 are rejected, and the 5
+	// after them produces -5.
+	assert.Len(t, small, 21)
+	for val, count := range small {
+		if val == -5 {
+			assert.Equal(t, 16, count)
+		} else {
+			assert.Equal(t, 12, count)
+		}
+	}
+
+	// A range wider than int8 is narrowed, so every int8 is produced
+	// exactly once
+	assert.Len(t, wide, 256)
+
+	// Likewise for uint8, from the range minimum up to 255. The 100 bytes
+	// after the first 156 are rejected, and the 5 after them produces 105.
+	assert.Len(t, uints, 156)
+	assert.Equal(t, 101, uints[105])
+}
+
+// Values drawn from a range are evenly distributed, even when the range
+// doesn't divide the values of the bytes consumed
+func TestFuzzTags_EvenRangeDistribution(t *testing.T) {
+	type evenStruct struct {
+		Values []int8 `fuzz-slice-range:"100000,100000" fuzz-int-range:"0,99"`
+	}
+
+	r := rand.New(rand.NewSource(1))
+	bytes := make([]byte, 0, 200_000)
+	for range cap(bytes) {
+		bytes = append(bytes, byte(r.Intn(256)))
+	}
+
+	for _, format := range []ByteFormat{NativeFormat, CompactFormat} {
+		val := evenStruct{}
+		Fill(&val, bytes, WithFormat(format), WithExhaustion(PRNGOnExhaustion))
+
+		counts := make([]int, 100)
+		for _, v := range val.Values {
+			counts[v]++
+		}
+		// Each value is expected 1000 times. Taking bytes modulo 100
+		// would produce 0 to 55 about 1170 times, and the rest about
+		// 780 times.
+		for v, count := range counts {
+			assert.InDelta(t, 1000, count, 150, "value %d", v)
+		}
+	}
+}
+
+func TestFuzzTags_OpenEndedRanges(t *testing.T) {
+	type openStruct struct {
+		MinField   int8    `fuzz-int-range:"100,"`
+		MaxField   int16   `fuzz-int-range:",-100"`
+		UintField  uint8   `fuzz-uint-range:"250,"`
+		FloatField float32 `fuzz-float-range:"0,"`
+		SliceField []int8  `fuzz-slice-range:"2,"`
+	}
+
+	for i := 0; i < 256; i++ {
+		bytes := make([]byte, 64)
+		for j := range bytes {
+			bytes[j] = byte(i + j)
+		}
+
+		val := openStruct{}
+		Fill(&val, bytes)
+
+		assert.GreaterOrEqual(t, val.MinField, int8(100))
+		assert.LessOrEqual(t, val.MaxField, int16(-100))
+		assert.GreaterOrEqual(t, val.UintField, uint8(250))
+		assert.GreaterOrEqual(t, val.FloatField, float32(0))
+		assert.LessOrEqual(t, val.FloatField, float32(math.MaxFloat32))
+		// An omitted length maximum is the default maximum above the
+		// minimum
+		assert.GreaterOrEqual(t, len(val.SliceField), 2)
+		assert.LessOrEqual(t, len(val.SliceField), 2+defaultLengthMax)
+	}
+}
+
+func TestFuzzTags_RangeLiterals(t *testing.T) {
+	type literalStruct struct {
+		HexField        int64   `fuzz-int-range:"-0x10,0x10"`
+		UnderscoreField uint64  `fuzz-uint-range:"1_000,1_000"`
+		FloatField      float64 `fuzz-float-range:"0xFF,0xFF"`
+	}
+
+	intRange := newIntTagRange(reflect.ValueOf(literalStruct{}), reflect.TypeOf(literalStruct{}).Field(0), "fuzz-int-range")
+	assert.Equal(t, int64(-16), intRange.intMin)
+	assert.Equal(t, int64(16), intRange.intMax)
+
+	val := literalStruct{}
+	Fill(&val, make([]byte, 24))
+	assert.Equal(t, int64(-16), val.HexField)
+	assert.Equal(t, uint64(1000), val.UnderscoreField)
+	assert.Equal(t, float64(255), val.FloatField)
+
+	// Literals with leading zeros, but no 0x, 0b or 0o prefix, are decimal
+	type leadingZeroStruct struct {
+		OctalLikeField int8    `fuzz-int-range:"010,012"`
+		DigitsField    uint8   `fuzz-uint-range:"01,09"`
+		BinaryField    int16   `fuzz-int-range:"0b11,0o7"`
+		FloatField     float32 `fuzz-float-range:"010,010"`
+	}
+	field := reflect.TypeOf(leadingZeroStruct{}).Field(0)
+	intRange = newIntTagRange(reflect.ValueOf(leadingZeroStruct{}), field, "fuzz-int-range")
+	assert.Equal(t, int64(10), intRange.intMin)
+	assert.Equal(t, int64(12), intRange.intMax)
+
+	zeros := leadingZeroStruct{}
+	Fill(&zeros, make([]byte, 24))
+	assert.Equal(t, leadingZeroStruct{OctalLikeField: 10, DigitsField: 1, BinaryField: 3, FloatField: 10}, zeros)
+}
+
+func TestFuzzTags_MultiIntervalRanges(t *testing.T) {
+	type unionStruct struct {
+		IntField    int64   `fuzz-int-range:"100,200|0,10|150,250|-5,-5"`
+		UintField   uint16  `fuzz-uint-range:"0,0|1,10|65535,65535"`
+		FloatField  float64 `fuzz-float-range:"0,1|10,11"`
+		StringField string  `fuzz-string-range:"0,0|5,5"`
+	}
+
+	ints := map[int64]int{}
+	uints := map[uint16]int{}
+	for i := 0; i < 512; i++ {
+		c := newByteConsumer([]byte{})
+		c.pushUint64(uint64(i), bytesFor64)
+		c.pushUint64(uint64(i), bytesFor16)
+		c.pushFloat64(float64(i)/10, bytesFor64)
+		c.pushInt64(int64(i), bytesForNative)
+		c.pushBytes([]byte("abcde"))
+
+		val := unionStruct{}
+		Fill(&val, c.getRawBytes())
+
+		ints[val.IntField]++
+		uints[val.UintField]++
+		assert.True(t, (val.FloatField >= 0 && val.FloatField <= 1) || (val.FloatField >= 10 && val.FloatField <= 11), val.FloatField)
+		assert.Contains(t, []int{0, 5}, len(val.StringField))
+	}
+
+	// -5, 0 to 10 and 100 to 250
+	assert.Len(t, ints, 1+11+151)
+	assert.Contains(t, ints, int64(-5))
+	assert.NotContains(t, ints, int64(11))
+	assert.NotContains(t, ints, int64(99))
+
+	assert.Len(t, uints, 12)
+	assert.Contains(t, uints, uint16(65535))
+}
+
+func TestFuzzTags_BadMultiIntervalRange(t *testing.T) {
+	type badUnionStruct struct {
+		IntField int8 `fuzz-int-range:"0,10|20,15"`
+	}
+
+	assert.PanicsWithError(t, `fuzzhelper.badUnionStruct.IntField has invalid fuzz-int-range "0,10|20,15", must be one or more min,max intervals separated by |`, func() {
+		Fill(&badUnionStruct{}, []byte{0xF0})
+	})
+}
+
+func TestFuzzTags_BadRange(t *testing.T) {
+	type badRangeStruct struct {
+		IntField int8 `fuzz-int-range:"1,x"`
+	}
+	assert.PanicsWithError(t, `fuzzhelper.badRangeStruct.IntField has invalid fuzz-int-range "1,x", must be one or more min,max intervals separated by |`, func() {
+		Fill(&badRangeStruct{}, []byte{1})
+	})
+
+	type badLengthStruct struct {
+		SliceField []int `fuzz-slice-range:"1"`
+	}
+	assert.PanicsWithError(t, `fuzzhelper.badLengthStruct.SliceField has invalid fuzz-slice-range "1", must be one or more min,max intervals separated by |`, func() {
+		Fill(&badLengthStruct{}, []byte{1})
+	})
+}
+
 type floatLimitStruct struct {
 	Float64FieldBigLimit  float64 `fuzz-float-range:"1000,2000"`
 	Float64FieldTinyLimit float64 `fuzz-float-range:"0.1,0.2"`
@@ -442,6 +685,101 @@ func TestFuzzTags_MapLengthKeysAndValues(t *testing.T) {
 	assert.Equal(t, expected, val)
 }
 
+type mapKeyValueStruct struct {
+	KeyValueMap map[string]int8 `fuzz-map-range:"2,2" fuzz-key-string-method:"Keys" fuzz-value-int-range:"0,9"`
+	SharedMap   map[int8]int8   `fuzz-map-range:"1,1" fuzz-int-range:"-5,-5"`
+	ValueMap    map[uint8][]int `fuzz-map-range:"1,1" fuzz-uint-range:"7,7" fuzz-value-slice-range:"2,2" fuzz-value-int-range:"1,1"`
+}
+
+func (s *mapKeyValueStruct) Keys() []string {
+	return []string{"a", "b", "c"}
+}
+
+func TestFuzzTags_MapKeyAndValueTags(t *testing.T) {
+	c := newByteConsumer([]byte{})
+
+	// KeyValueMap of size 2
+	c.pushUint64(2, bytesForNative)
+	// First key "c" and value 4
+	c.pushChoice(2, 3, bytesForNative)
+	c.pushInt64(4, bytesFor8)
+	// Second key "a" and value 9
+	c.pushChoice(0, 3, bytesForNative)
+	c.pushInt64(9, bytesFor8)
+
+	// SharedMap of size 1, both key and value use fuzz-int-range
+	c.pushUint64(1, bytesForNative)
+	c.pushInt64(0, bytesFor8)
+	c.pushInt64(0, bytesFor8)
+
+	// ValueMap of size 1, the key uses fuzz-uint-range, the value only
+	// uses the fuzz-value- tags
+	c.pushUint64(1, bytesForNative)
+	c.pushUint64(0, bytesFor8)
+	c.pushUint64(2, bytesForNative)
+	c.pushInt64(0, bytesForNative)
+	c.pushInt64(0, bytesForNative)
+
+	val := mapKeyValueStruct{}
+	Fill(&val, c.getRawBytes())
+
+	assert.Equal(t, mapKeyValueStruct{
+		KeyValueMap: map[string]int8{"c": 4, "a": 9},
+		SharedMap:   map[int8]int8{-5: -5},
+		ValueMap:    map[uint8][]int{7: {1, 1}},
+	}, val)
+}
+
+type levelStruct struct {
+	Grid  [][]int8            `fuzz-slice-range:"2,2;3,3" fuzz-int-range:";;0,9"`
+	Index map[string][]string `fuzz-map-range:"1,1" fuzz-string-range:"1,1;;2,2" fuzz-slice-range:";1,1"`
+	Ptrs  []*[]uint8          `fuzz-slice-range:"1,1;2,2" fuzz-uint-range:";;5,5"`
+	Every [][]int8            `fuzz-slice-range:"1,1;2,2" fuzz-int-range:"3,3"`
+}
+
+func TestFuzzTags_NestedLevels(t *testing.T) {
+	bytes := []byte{}
+	for range 1000 {
+		bytes = append(bytes, 7)
+	}
+
+	val := levelStruct{}
+	Fill(&val, bytes)
+
+	assert.Equal(t, [][]int8{{7, 7, 7}, {7, 7, 7}}, val.Grid)
+	assert.Equal(t, map[string][]string{"\x07": {"\x07\x07"}}, val.Index)
+	require.Len(t, val.Ptrs, 1)
+	assert.Equal(t, []uint8{5, 5}, *val.Ptrs[0])
+	// Tags without levels apply at every level
+	assert.Equal(t, [][]int8{{3, 3}}, val.Every)
+
+	encoded, err := Encode(&val)
+	require.NoError(t, err)
+	filled := levelStruct{}
+	Fill(&filled, encoded)
+	assert.Equal(t, val, filled)
+}
+
+func TestFuzzTags_TagLevels(t *testing.T) {
+	levels := tagLevels(`fuzz-slice-range:"1,3;0,10" fuzz-int-range:"0,9" fuzz-id:"1" json:"field"`)
+	assert.Equal(t, []reflect.StructTag{
+		`fuzz-slice-range:"1,3" fuzz-int-range:"0,9" fuzz-id:"1"`,
+		`fuzz-slice-range:"0,10" fuzz-int-range:"0,9" fuzz-id:"1"`,
+		`fuzz-int-range:"0,9" fuzz-id:"1"`,
+	}, levels)
+
+	assert.Nil(t, tagLevels(`fuzz-slice-range:"1,3" fuzz-int-range:"0,9"`))
+
+	// Only tags which take levels are split
+	assert.Nil(t, tagLevels(`fuzz-weights:"1;2" fuzz-string-method:"A;B" fuzz-unknown:"a;b" other:"a;b"`))
+	levels = tagLevels(`fuzz-key-int-range:"0,1;2,3" fuzz-charset:"[;a]"`)
+	assert.Equal(t, []reflect.StructTag{
+		`fuzz-key-int-range:"0,1" fuzz-charset:"[;a]"`,
+		`fuzz-key-int-range:"2,3" fuzz-charset:"[;a]"`,
+		`fuzz-charset:"[;a]"`,
+	}, levels)
+}
+
 type interfaceDemo interface {
 	InterfaceMethod() string
 }
@@ -112,6 +112,56 @@ func (b badMethodNotExported) intOptions() []int {
 	return []int{1, 2, 3}
 }
 
+type badKeyTagsNotMap struct {
+	SliceField []int `fuzz-key-int-range:"0,1"`
+}
+
+type badKeyMethodAssignment struct {
+	MapField map[string]int `fuzz-key-int-method:"IntOptions"`
+}
+
+func (s *badKeyMethodAssignment) IntOptions() []int {
+	return []int{1, 2, 3}
+}
+
+type badMapMethodAssignment struct {
+	MapField map[string]int `fuzz-float-method:"FloatOptions"`
+}
+
+func (s *badMapMethodAssignment) FloatOptions() []float64 {
+	return []float64{1, 2, 3}
+}
+
+// Method options on a map must be assignable to the keys and the values
+type badMapKeyMethodAssignment struct {
+	MapField map[int8]int64 `fuzz-int-method:"IntOptions"`
+}
+
+func (s *badMapKeyMethodAssignment) IntOptions() []int64 {
+	return []int64{1000, 2000, 3000}
+}
+
+// Ranges must contain values of the type they fill
+type badIntRangeOutsideType struct {
+	IntField int8 `fuzz-int-range:"1000,2000"`
+}
+
+type badUintRangeOutsideType struct {
+	UintField uint8 `fuzz-uint-range:"300,400"`
+}
+
+type badIntRangeOutsideElementType struct {
+	SliceField []int16 `fuzz-slice-range:"1,1" fuzz-int-range:"-70000,-40000|40000,70000"`
+}
+
+type badKeyRangeOutsideType struct {
+	MapField map[uint8]uint64 `fuzz-key-uint-range:"256,512"`
+}
+
+type badLevelRangeOutsideType struct {
+	GridField [][]uint16 `fuzz-slice-range:"1,1;1,1" fuzz-uint-range:";;70000,80000"`
+}
+
 func TestFuzzTags_Bad(t *testing.T) {
 	// The value of these bytes don't matter, but we do need _some_ bytes in order to reach the tags and expose the errors
 	bytes := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0}
@@ -170,6 +220,51 @@ func TestFuzzTags_Bad(t *testing.T) {
 			expectedError: "fuzzhelper.badMethodNotExported.intOptions() is not exported and can't be called",
 			value:         &badMethodNotExported{},
 		},
+		{
+			name:          "key tags on a field without a map",
+			expectedError: "fuzzhelper.badKeyTagsNotMap.SliceField has fuzz-key- or fuzz-value- tags, but []int does not contain a map",
+			value:         &badKeyTagsNotMap{},
+		},
+		{
+			name:          "key method with the wrong type",
+			expectedError: "fuzzhelper.badKeyMethodAssignment.MapField cannot be assigned by every value returned by fuzzhelper.badKeyMethodAssignment.IntOptions(), value of type int cannot be assigned to string",
+			value:         &badKeyMethodAssignment{},
+		},
+		{
+			name:          "map method assignable to neither keys nor values",
+			expectedError: "fuzzhelper.badMapMethodAssignment.MapField cannot be assigned by every value returned by fuzzhelper.badMapMethodAssignment.FloatOptions(), value of type float64 cannot be assigned to string",
+			value:         &badMapMethodAssignment{},
+		},
+		{
+			name:          "map method assignable to the values but not the keys",
+			expectedError: "fuzzhelper.badMapKeyMethodAssignment.MapField cannot be assigned by every value returned by fuzzhelper.badMapKeyMethodAssignment.IntOptions(), value of type int64 cannot be assigned to int8",
+			value:         &badMapKeyMethodAssignment{},
+		},
+		{
+			name:          "int range outside the field's type",
+			expectedError: `fuzzhelper.badIntRangeOutsideType.IntField has fuzz-int-range "1000,2000", which has no values of type int8`,
+			value:         &badIntRangeOutsideType{},
+		},
+		{
+			name:          "uint range outside the field's type",
+			expectedError: `fuzzhelper.badUintRangeOutsideType.UintField has fuzz-uint-range "300,400", which has no values of type uint8`,
+			value:         &badUintRangeOutsideType{},
+		},
+		{
+			name:          "int range outside the element type",
+			expectedError: `fuzzhelper.badIntRangeOutsideElementType.SliceField has fuzz-int-range "-70000,-40000|40000,70000", which has no values of type int16`,
+			value:         &badIntRangeOutsideElementType{},
+		},
+		{
+			name:          "key range outside the key type",
+			expectedError: `fuzzhelper.badKeyRangeOutsideType.MapField has fuzz-key-uint-range "256,512", which has no values of type uint8`,
+			value:         &badKeyRangeOutsideType{},
+		},
+		{
+			name:          "level range outside the element type",
+			expectedError: `fuzzhelper.badLevelRangeOutsideType.GridField has fuzz-uint-range "70000,80000", which has no values of type uint16`,
+			value:         &badLevelRangeOutsideType{},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
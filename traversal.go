@@ -0,0 +1,54 @@
+package fuzzhelper
+
+// TraversalOrder controls the order in which Fill visits the values nested
+// inside a value, and so which bytes of the input are used for each value.
+type TraversalOrder int
+
+const (
+	// BreadthFirst fills every field of a struct before filling the values
+	// pointed to by its pointer and interface fields. Unbounded slices
+	// grow by one element each time every other pending value has been
+	// filled. This is the default order.
+	BreadthFirst TraversalOrder = iota
+
+	// DepthFirst fills the value pointed to by a pointer or interface
+	// field immediately after the field, before the next field. The bytes
+	// for a nested struct sit next to each other in the input, which
+	// keeps mutations local and makes inputs easier to write by hand.
+	// Unbounded slices grow as for BreadthFirst, so that they don't
+	// consume the bytes for the fields which follow them.
+	DepthFirst
+
+	// DeclarationOrder fills values in the order they would appear in a
+	// printed struct literal, as for DepthFirst, except that unbounded
+	// slices also grow immediately. An unbounded slice keeps growing
+	// until the bytes run out, so any fields following it are only
+	// filled by the ExhaustionPolicy.
+	DeclarationOrder
+)
+
+// WithTraversal sets the order in which nested values are filled.
+func WithTraversal(order TraversalOrder) Option {
+	return func(config *fillConfig) {
+		config.traversal = order
+	}
+}
+
+// Returns the visits needed to fill the value held by a pointer or
+// interface. These are deferred until the enclosing values are filled, or
+// run immediately.
+func (order TraversalOrder) nested(visit visitFunc) []visitFunc {
+	if order == BreadthFirst {
+		return []visitFunc{visit}
+	}
+	return visit()
+}
+
+// Returns the visits needed to grow an unbounded slice. These are deferred
+// until every other pending value is filled, or run immediately.
+func (order TraversalOrder) growth(visit visitFunc) []visitFunc {
+	if order == DeclarationOrder {
+		return visit()
+	}
+	return []visitFunc{visit}
+}
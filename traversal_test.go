@@ -0,0 +1,77 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type traversalOuter struct {
+	Inner *traversalInner
+	Tail  []int8
+	After int8
+}
+
+type traversalInner struct {
+	Value int8
+}
+
+func TestFill_TraversalOrder(t *testing.T) {
+	for _, tc := range []struct {
+		order    TraversalOrder
+		expected traversalOuter
+	}{
+		{
+			order: BreadthFirst,
+			expected: traversalOuter{
+				Inner: &traversalInner{Value: 3},
+				Tail:  []int8{1, 4},
+				After: 2,
+			},
+		},
+		{
+			order: DepthFirst,
+			expected: traversalOuter{
+				Inner: &traversalInner{Value: 1},
+				Tail:  []int8{2, 4},
+				After: 3,
+			},
+		},
+		{
+			order: DeclarationOrder,
+			expected: traversalOuter{
+				Inner: &traversalInner{Value: 1},
+				Tail:  []int8{2, 3, 4},
+				After: 0,
+			},
+		},
+	} {
+		val := traversalOuter{}
+		Fill(&val, []byte{1, 2, 3, 4}, WithTraversal(tc.order))
+		assert.Equal(t, tc.expected, val, "order %d", tc.order)
+	}
+}
+
+func TestEncode_TraversalOrder(t *testing.T) {
+	val := traversalOuter{
+		Inner: &traversalInner{Value: 5},
+		Tail:  []int8{6},
+		After: 7,
+	}
+
+	encoded, err := Encode(&val, WithTraversal(DepthFirst))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{5, 6, 7}, encoded)
+
+	filled := traversalOuter{}
+	Fill(&filled, encoded, WithTraversal(DepthFirst))
+	assert.Equal(t, val, filled)
+}
+
+func TestFingerprint_TraversalOrder(t *testing.T) {
+	breadthFirst := Fingerprint(&traversalOuter{})
+	assert.Equal(t, breadthFirst, Fingerprint(&traversalOuter{}, WithTraversal(BreadthFirst)))
+	assert.NotEqual(t, breadthFirst, Fingerprint(&traversalOuter{}, WithTraversal(DepthFirst)))
+	assert.NotEqual(t, Fingerprint(&traversalOuter{}, WithTraversal(DepthFirst)), Fingerprint(&traversalOuter{}, WithTraversal(DeclarationOrder)))
+}
@@ -19,35 +19,59 @@ type valueVisitor interface {
 	visitFunc(reflect.Value, fuzzTags, valuePath)
 	visitInterface(reflect.Value, *byteConsumer, fuzzTags, valuePath) bool
 	visitMap(reflect.Value, *byteConsumer, fuzzTags, valuePath) int
-	visitPointer(reflect.Value, *byteConsumer, fuzzTags, valuePath)
+	// Returns the key and value for the entry at index in a map. The
+	// returned key and value are visited and then added to the map.
+	mapEntry(mapValue reflect.Value, index int) (key, val reflect.Value)
+	// Called after the key of a map entry is visited, before its value is
+	// visited and the entry is added to the map
+	visitMapKey(mapValue, key reflect.Value, tags fuzzTags)
+	// Returns false if the value pointed to should not be visited, e.g.
+	// because the pointer reuses a value which has already been visited
+	visitPointer(reflect.Value, *byteConsumer, fuzzTags, valuePath) bool
 	visitSlice(reflect.Value, *byteConsumer, fuzzTags, valuePath) (from, to int)
+	// Called for slices and arrays of bytes. Returns false if the bytes
+	// should be visited one element at a time.
+	visitBytes(reflect.Value, *byteConsumer, fuzzTags, valuePath) bool
+	// Called after the elements of a slice or array are visited
+	visitElements(reflect.Value, fuzzTags, valuePath)
 	visitString(reflect.Value, *byteConsumer, fuzzTags, valuePath)
 	visitStruct(reflect.Value, fuzzTags, valuePath) bool
 	visitUnsafePointer(reflect.Value, fuzzTags, valuePath)
+	// Called before visiting a value when there are no bytes left. Returns
+	// true if the value should still be visited.
+	visitExhausted(reflect.Value, fuzzTags, valuePath) bool
 }
 
-func newVisitFunc(callback valueVisitor, value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) visitFunc {
+func newVisitFunc(callback valueVisitor, value reflect.Value, c *byteConsumer, order TraversalOrder, tags fuzzTags, path valuePath) visitFunc {
 	return func() []visitFunc {
 		//println(fmt.Sprintf("before %#v\n", value.Interface()))
-		ffs := visitValue(callback, value, c, tags, path)
+		ffs := visitValue(callback, value, c, order, tags, path)
 		//println(fmt.Sprintf("after %#v\n", value.Interface()))
 		return ffs
 	}
 }
 
-func visitRoot(callback valueVisitor, root any, c *byteConsumer) {
+// Creates a new, settable, key and value for mapValue
+func newMapEntry(mapValue reflect.Value) (key, val reflect.Value) {
+	mapType := mapValue.Type()
+	return reflect.New(mapType.Key()).Elem(), reflect.New(mapType.Elem()).Elem()
+}
+
+func visitRoot(callback valueVisitor, root any, c *byteConsumer, order TraversalOrder) {
 	rootVal := reflect.ValueOf(root)
 
 	path := valuePath{}
-	visitBreadthFirst(callback, rootVal, c, path)
+	visitInOrder(callback, rootVal, c, order, path)
 
 	//println("")
 }
 
-func visitBreadthFirst(callback valueVisitor, value reflect.Value, c *byteConsumer, path valuePath) {
+// Visits value, and then every visit it defers, in the order they are
+// deferred
+func visitInOrder(callback valueVisitor, value reflect.Value, c *byteConsumer, order TraversalOrder, path valuePath) {
 	values := newDeque[visitFunc]()
 
-	visitFuncs := visitValue(callback, value, c, newEmptyFuzzTags(), path)
+	visitFuncs := visitValue(callback, value, c, order, newEmptyFuzzTags(), path)
 	values.addMany(visitFuncs)
 
 	for values.len() != 0 {
@@ -57,8 +81,8 @@ func visitBreadthFirst(callback valueVisitor, value reflect.Value, c *byteConsum
 	}
 }
 
-func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, tags fuzzTags, path valuePath) []visitFunc {
-	if c.len() == 0 {
+func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, order TraversalOrder, tags fuzzTags, path valuePath) []visitFunc {
+	if c.len() == 0 && !callback.visitExhausted(value, tags, path) {
 		// There are no more bytes to use to visit data
 		return []visitFunc{}
 	}
@@ -91,11 +115,17 @@ func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, tag
 	case reflect.Array:
 		callback.visitArray(value, tags, path)
 
+		if isBytes(value) && callback.visitBytes(value, c, tags, path) {
+			callback.visitElements(value, tags, path)
+			return []visitFunc{}
+		}
+
 		newValues := []visitFunc{}
 		for i := 0; i < value.Len(); i++ {
 			pathVal := fmt.Sprintf("[%d]", i)
-			newValues = append(newValues, visitValue(callback, value.Index(i), c, tags, path.add(value, pathVal))...)
+			newValues = append(newValues, visitValue(callback, value.Index(i), c, order, tags.elementTags(), path.add(value, pathVal))...)
 		}
+		callback.visitElements(value, tags, path)
 		return newValues
 
 	case reflect.Chan:
@@ -108,9 +138,7 @@ func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, tag
 
 	case reflect.Interface:
 		if callback.visitInterface(value, c, tags, path) {
-			return []visitFunc{
-				newVisitFunc(callback, value.Elem(), c, newEmptyFuzzTags(), path.add(value, "(ifc)")),
-			}
+			return order.nested(newVisitFunc(callback, value.Elem(), c, order, newEmptyFuzzTags(), path.add(value, "(ifc)")))
 		} else {
 			return []visitFunc{}
 		}
@@ -118,25 +146,22 @@ func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, tag
 	case reflect.Map:
 		mapLen := callback.visitMap(value, c, tags, path)
 
-		mapType := value.Type()
-		keyType := mapType.Key()
-		valType := mapType.Elem()
-
 		newValues := []visitFunc{}
-		for range mapLen {
+		for i := range mapLen {
+			mapKey, mapVal := callback.mapEntry(value, i)
+
 			// Create the key
-			mapKeyP := reflect.New(keyType)
-			mapKey := mapKeyP.Elem()
-			// Note here that the tags used to create this map are also
-			// used to create the key
-			newValues = append(newValues, visitValue(callback, mapKey, c, tags, path.add(value, "[key]"))...)
+			// Note here that, unless there are fuzz-key- tags, the
+			// tags used to create this map are also used to create
+			// the key
+			newValues = append(newValues, visitValue(callback, mapKey, c, order, tags.mapKeyTags(), path.add(value, "[key]"))...)
+			callback.visitMapKey(value, mapKey, tags)
 
 			// Create the value
-			mapValP := reflect.New(valType)
-			mapVal := mapValP.Elem()
-			// Note here that the tags used to create this map are also
-			// used to create the value
-			newValues = append(newValues, visitValue(callback, mapVal, c, tags, path.add(value, "[value]"))...)
+			// Note here that, unless there are fuzz-value- tags,
+			// the tags used to create this map are also used to
+			// create the value
+			newValues = append(newValues, visitValue(callback, mapVal, c, order, tags.mapValueTags(), path.add(value, "[value]"))...)
 
 			// Add key/val to map
 			//println("setting map element")
@@ -146,12 +171,19 @@ func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, tag
 		return newValues
 
 	case reflect.Pointer:
-		callback.visitPointer(value, c, tags, path)
-		return []visitFunc{
-			newVisitFunc(callback, value.Elem(), c, newEmptyFuzzTags(), path.add(value, "*")),
+		if !callback.visitPointer(value, c, tags, path) || value.IsNil() {
+			// The visitor elected not to allocate a value for this
+			// pointer, or reused one, there is nothing to visit
+			return []visitFunc{}
 		}
+		return order.nested(newVisitFunc(callback, value.Elem(), c, order, tags.pointeeTags(), path.add(value, "*")))
 
 	case reflect.Slice:
+		if isBytes(value) && callback.visitBytes(value, c, tags, path) {
+			callback.visitElements(value, tags, path)
+			return []visitFunc{}
+		}
+
 		from, to := callback.visitSlice(value, c, tags, path)
 
 		// Add a single element to the slice (which should be non-nil now)
@@ -164,15 +196,16 @@ func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, tag
 		// Fill in all elements.
 		for i := from; i < to; i++ {
 			pathVal := fmt.Sprintf("[%d]", i)
-			newValues = append(newValues, visitValue(callback, value.Index(i), c, tags, path.add(value, pathVal))...)
+			newValues = append(newValues, visitValue(callback, value.Index(i), c, order, tags.elementTags(), path.add(value, pathVal))...)
 		}
+		callback.visitElements(value, tags, path)
 
 		if !tags.sliceRange.uintRange.wasSet && from != to {
 			// This slice has an unbounded size.  Create a
 			// recursive callback to this slice, to allow more
 			// elements to be appended to the slice if there is
 			// enough data
-			newValues = append(newValues, newVisitFunc(callback, value, c, tags, path))
+			newValues = append(newValues, order.growth(newVisitFunc(callback, value, c, order, tags, path))...)
 		}
 
 		return newValues
@@ -197,11 +230,11 @@ func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, tag
 		newValues := []visitFunc{}
 		vType := value.Type()
 		path = path.add(value, "("+vType.Name()+")")
-		for i := 0; i < vType.NumField(); i++ {
+		for _, i := range fieldOrder(vType) {
 			vField := value.Field(i)
 			tField := vType.Field(i)
 			tags := newFuzzTags(value, tField)
-			newValues = append(newValues, visitValue(callback, vField, c, tags, path.add(value, tField.Name))...)
+			newValues = append(newValues, visitValue(callback, vField, c, order, tags, path.add(value, tField.Name))...)
 		}
 
 		return newValues
@@ -213,6 +246,4 @@ func visitValue(callback valueVisitor, value reflect.Value, c *byteConsumer, tag
 	default:
 		panic(fmt.Errorf("unsupported kind %s", value.Kind()))
 	}
-
-	panic("unreachable")
 }
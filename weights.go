@@ -0,0 +1,102 @@
+package fuzzhelper
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A fuzz-weights tag gives the relative weight of each of the options
+// returned by a method tag, e.g. fuzz-weights:"10,1" makes the first of two
+// options ten times more likely than the second.
+//
+// Choosing between weighted options is a choice between as many options as
+// the total of the weights, each option taking as many of these as its
+// weight.
+type weightsTag struct {
+	wasSet  bool
+	weights []int
+	total   int
+}
+
+func newWeightsTag(structVal reflect.Value, field reflect.StructField, tag string, t fuzzTags) weightsTag {
+	valStr, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return weightsTag{}
+	}
+
+	options, ok := optionCount(t)
+	if !ok {
+		panic(fmt.Errorf("%s.%s has %s, but no method tag to weight", structVal.Type(), field.Name, tag))
+	}
+
+	weights := []int{}
+	total := 0
+	for _, weightStr := range strings.Split(valStr, ",") {
+		weight, err := strconv.Atoi(weightStr)
+		if err != nil || weight <= 0 {
+			panic(fmt.Errorf("%s.%s has invalid %s %q, weights must be positive integers", structVal.Type(), field.Name, tag, valStr))
+		}
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	if len(weights) != options {
+		panic(fmt.Errorf("%s.%s has %d %s for %d method options", structVal.Type(), field.Name, len(weights), tag, options))
+	}
+
+	return weightsTag{
+		wasSet:  true,
+		weights: weights,
+		total:   total,
+	}
+}
+
+// Returns the number of options returned by the method tag in t
+func optionCount(t fuzzTags) (int, bool) {
+	switch {
+	case t.intValues.wasSet:
+		return len(t.intValues.value), true
+	case t.uintValues.wasSet:
+		return len(t.uintValues.value), true
+	case t.floatValues.wasSet:
+		return len(t.floatValues.value), true
+	case t.stringValues.wasSet:
+		return len(t.stringValues.value), true
+	case t.interfaceValues.wasSet:
+		return len(t.interfaceValues.value), true
+	default:
+		return 0, false
+	}
+}
+
+// Returns the option which owns the weighted choice slot
+func (t weightsTag) option(slot int) int {
+	for i, weight := range t.weights {
+		if slot < weight {
+			return i
+		}
+		slot -= weight
+	}
+	panic(fmt.Errorf("weighted choice %d is out of range for total weight %d", slot, t.total))
+}
+
+// Returns the first weighted choice slot owned by option, the inverse of
+// option
+func (t weightsTag) slot(option int) int {
+	slot := 0
+	for _, weight := range t.weights[:option] {
+		slot += weight
+	}
+	return slot
+}
+
+// Describes the probability of choosing each option
+func (t weightsTag) String() string {
+	parts := []string{}
+	for _, weight := range t.weights {
+		parts = append(parts, fmt.Sprintf("%d (%.1f%%)", weight, 100*float64(weight)/float64(t.total)))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,99 @@
+package fuzzhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type weightedStruct struct {
+	IntField       int           `fuzz-int-method:"IntOptions" fuzz-weights:"10,1"`
+	InterfaceField interfaceDemo `fuzz-interface-method:"InterfaceOptions" fuzz-weights:"1,3,1"`
+}
+
+func (s *weightedStruct) IntOptions() []int {
+	return []int{1, 2}
+}
+
+func (s *weightedStruct) InterfaceOptions() []interfaceDemo {
+	return []interfaceDemo{&interfaceDemoA{}, &interfaceDemoB{}, &interfaceDemoC{}}
+}
+
+func TestFill_Weights(t *testing.T) {
+	ints := map[int]int{}
+	interfaces := map[string]int{}
+	for i := 0; i < 11*5; i++ {
+		c := newByteConsumer([]byte{})
+		c.pushUint64(uint64(i%11), bytesFor8)
+		c.pushUint64(uint64(i%5), bytesFor8)
+
+		val := weightedStruct{}
+		Fill(&val, c.getRawBytes(), WithFormat(CompactFormat))
+
+		ints[val.IntField]++
+		switch val.InterfaceField.(type) {
+		case *interfaceDemoA:
+			interfaces["A"]++
+		case *interfaceDemoB:
+			interfaces["B"]++
+		case *interfaceDemoC:
+			interfaces["C"]++
+		}
+	}
+
+	assert.Equal(t, map[int]int{1: 50, 2: 5}, ints)
+	assert.Equal(t, map[string]int{"A": 11, "B": 33, "C": 11}, interfaces)
+}
+
+func TestEncode_Weights(t *testing.T) {
+	val := weightedStruct{
+		IntField:       2,
+		InterfaceField: &interfaceDemoC{},
+	}
+
+	for _, opts := range [][]Option{
+		{},
+		{WithFormat(CompactFormat)},
+		{WithBitPacking(true)},
+	} {
+		encoded, err := Encode(&val, opts...)
+		require.NoError(t, err)
+
+		filled := weightedStruct{}
+		Fill(&filled, encoded, opts...)
+		assert.Equal(t, val, filled)
+	}
+}
+
+type badWeightsCount struct {
+	IntField int `fuzz-int-method:"IntOptions" fuzz-weights:"1,2,3"`
+}
+
+func (s *badWeightsCount) IntOptions() []int {
+	return []int{1, 2}
+}
+
+type badWeightsValue struct {
+	IntField int `fuzz-int-method:"IntOptions" fuzz-weights:"1,0"`
+}
+
+func (s *badWeightsValue) IntOptions() []int {
+	return []int{1, 2}
+}
+
+type badWeightsNoMethod struct {
+	IntField int `fuzz-weights:"1,2"`
+}
+
+func TestFill_BadWeights(t *testing.T) {
+	assert.PanicsWithError(t, "fuzzhelper.badWeightsCount.IntField has 3 fuzz-weights for 2 method options", func() {
+		Fill(&badWeightsCount{}, []byte{1})
+	})
+	assert.PanicsWithError(t, `fuzzhelper.badWeightsValue.IntField has invalid fuzz-weights "1,0", weights must be positive integers`, func() {
+		Fill(&badWeightsValue{}, []byte{1})
+	})
+	assert.PanicsWithError(t, "fuzzhelper.badWeightsNoMethod.IntField has fuzz-weights, but no method tag to weight", func() {
+		Fill(&badWeightsNoMethod{}, []byte{1})
+	})
+}